@@ -46,6 +46,59 @@ type ProviderConfigSpec struct {
 	// +optional
 	// +kubebuilder:default=true
 	PluginCache *bool `json:"pluginCache,omitempty"`
+
+	// OCIPullSecrets are Secrets of type kubernetes.io/dockerconfigjson that
+	// are used to authenticate to registries when fetching modules from
+	// source 'OCI'.
+	// +optional
+	OCIPullSecrets []xpv1.SecretReference `json:"ociPullSecrets,omitempty"`
+
+	// GitSSH configures the SSH credentials that are used to fetch modules
+	// from git repositories over SSH, both by the provider and by Terraform.
+	// +optional
+	GitSSH *GitSSHCredentials `json:"gitSSH,omitempty"`
+
+	// RegistryCredentials are API tokens that are used to authenticate to
+	// private Terraform registries, for example to install modules and
+	// providers. They're passed to Terraform as TF_TOKEN_* environment
+	// variables, and take precedence over the credentials blocks of a
+	// .terraformrc file.
+	// +optional
+	// +listType=map
+	// +listMapKey=hostname
+	RegistryCredentials []RegistryCredentials `json:"registryCredentials,omitempty"`
+
+	// MaxConcurrentRuns is the maximum number of Terraform processes that
+	// may run at once for all Workspaces that use this provider config, on
+	// each provider replica. Workspaces wait for a running process to finish
+	// before they run another. There is no maximum if omitted.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrentRuns *int `json:"maxConcurrentRuns,omitempty"`
+}
+
+// GitSSHCredentials are used to authenticate to git repositories over SSH.
+type GitSSHCredentials struct {
+	// PrivateKeySecretRef references a Secret key that contains a private
+	// key in OpenSSH or PEM format.
+	PrivateKeySecretRef xpv1.SecretKeySelector `json:"privateKeySecretRef"`
+
+	// KnownHostsSecretRef references a Secret key that contains the known
+	// host keys of git repositories, in known_hosts format. Host keys are
+	// accepted the first time they're seen if known hosts are omitted.
+	// +optional
+	KnownHostsSecretRef *xpv1.SecretKeySelector `json:"knownHostsSecretRef,omitempty"`
+}
+
+// RegistryCredentials are used to authenticate to a Terraform registry.
+type RegistryCredentials struct {
+	// Hostname of the registry, for example app.terraform.io.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`
+	Hostname string `json:"hostname"`
+
+	// TokenSecretRef references a Secret key that contains an API token for
+	// the registry.
+	TokenSecretRef xpv1.SecretKeySelector `json:"tokenSecretRef"`
 }
 
 // ProviderCredentials required to authenticate.
@@ -0,0 +1,138 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TypeModuleVerified indicates whether a Workspace's module was verified.
+const TypeModuleVerified xpv1.ConditionType = "ModuleVerified"
+
+// Reasons a Workspace's module is or is not verified.
+const (
+	ReasonModuleVerified           xpv1.ConditionReason = "Verified"
+	ReasonModuleVerificationFailed xpv1.ConditionReason = "VerificationFailed"
+)
+
+// ModuleVerified returns a condition indicating that a Workspace's module
+// was verified.
+func ModuleVerified() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeModuleVerified,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonModuleVerified,
+	}
+}
+
+// ModuleVerificationFailed returns a condition indicating that a Workspace's
+// module could not be verified.
+func ModuleVerificationFailed(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeModuleVerified,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonModuleVerificationFailed,
+		Message:            err.Error(),
+	}
+}
+
+// TypeDiskQuota indicates whether a Workspace's working directories are within
+// the maximum disk usage.
+const TypeDiskQuota xpv1.ConditionType = "DiskQuota"
+
+// Reasons a Workspace's working directories are or are not within the maximum
+// disk usage.
+const (
+	ReasonWithinDiskQuota   xpv1.ConditionReason = "WithinQuota"
+	ReasonDiskQuotaExceeded xpv1.ConditionReason = "QuotaExceeded"
+)
+
+// WithinDiskQuota returns a condition indicating that a Workspace's working
+// directories are within the maximum disk usage.
+func WithinDiskQuota() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDiskQuota,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonWithinDiskQuota,
+	}
+}
+
+// DiskQuotaExceeded returns a condition indicating that a Workspace's working
+// directories exceed the maximum disk usage.
+func DiskQuotaExceeded(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDiskQuota,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDiskQuotaExceeded,
+		Message:            err.Error(),
+	}
+}
+
+// TypeOutputKeys indicates whether the keys under which a Workspace's outputs
+// are published are valid ConfigMap and Secret keys.
+const TypeOutputKeys xpv1.ConditionType = "OutputKeys"
+
+// Reasons the keys of a Workspace's outputs are or are not valid.
+const (
+	ReasonOutputKeysValid   xpv1.ConditionReason = "Valid"
+	ReasonOutputKeysInvalid xpv1.ConditionReason = "Invalid"
+)
+
+// OutputKeysValid returns a condition indicating that the keys of a
+// Workspace's outputs are valid.
+func OutputKeysValid() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeOutputKeys,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonOutputKeysValid,
+	}
+}
+
+// OutputKeysInvalid returns a condition indicating that some keys of a
+// Workspace's outputs are invalid, and so those outputs weren't published.
+func OutputKeysInvalid(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeOutputKeys,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonOutputKeysInvalid,
+		Message:            err.Error(),
+	}
+}
+
+// ReasonOperationsPaused indicates that a Workspace's readiness is unknown
+// because its Terraform operations are paused.
+const ReasonOperationsPaused xpv1.ConditionReason = "OperationsPaused"
+
+// OperationsPaused returns a condition indicating that a Workspace isn't known
+// to be ready, because its Terraform operations are paused and so it wasn't
+// planned.
+func OperationsPaused() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               xpv1.TypeReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonOperationsPaused,
+	}
+}
@@ -19,6 +19,7 @@ package v1beta1
 import (
 	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
 	xpv2 "github.com/crossplane/crossplane-runtime/v2/apis/common/v2"
+	corev1 "k8s.io/api/core/v1"
 	extensionsV1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
@@ -91,35 +92,148 @@ type KeyReference struct {
 }
 
 // A ModuleSource represents the source of a Terraform module.
-// +kubebuilder:validation:Enum=Remote;Inline;Flux
+// +kubebuilder:validation:Enum=Remote;Inline;Flux;OCI;ConfigMap
 type ModuleSource string
 
 // Module sources.
 const (
-	ModuleSourceRemote ModuleSource = "Remote"
-	ModuleSourceInline ModuleSource = "Inline"
-	ModuleSourceFlux   ModuleSource = "Flux"
+	ModuleSourceRemote    ModuleSource = "Remote"
+	ModuleSourceInline    ModuleSource = "Inline"
+	ModuleSourceFlux      ModuleSource = "Flux"
+	ModuleSourceOCI       ModuleSource = "OCI"
+	ModuleSourceConfigMap ModuleSource = "ConfigMap"
 )
 
+// An OutputMapping selects a Terraform output and the key it is published
+// under.
+type OutputMapping struct {
+	// Name of the Terraform output.
+	Name string `json:"name"`
+
+	// Key under which the output is published. Defaults to the name of the
+	// output. When Flatten is true the key is used as a prefix.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Flatten an object or map output into one key per attribute or map key,
+	// named after the path to it. For example an output 'db' with attributes
+	// 'host' and 'port' is published as 'db.host' and 'db.port'. Nested
+	// objects and maps are flattened recursively. Outputs of any other type
+	// are unaffected.
+	// +optional
+	Flatten bool `json:"flatten,omitempty"`
+}
+
+// An OutputsConfigMap specifies a ConfigMap to which non-sensitive Terraform
+// outputs are published.
+type OutputsConfigMap struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Outputs to publish to the ConfigMap. All non-sensitive outputs are
+	// published when omitted. Sensitive outputs are never published.
+	// +optional
+	Outputs []OutputMapping `json:"outputs,omitempty"`
+}
+
+// A PublishedConfigMap identifies a ConfigMap to which outputs were published.
+type PublishedConfigMap struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+}
+
+// A ModuleConfigMap specifies a ConfigMap in the Workspace's namespace from
+// which (part of) a module is read.
+type ModuleConfigMap struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Path, relative to the root module, to which the files in the
+	// ConfigMap are written. Defaults to the root module.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// A ModuleVerification verifies the content of a module before it is used.
+// The module is verified against a manifest of its files, in the format
+// produced by sha256sum. Each line of the manifest contains the hex encoded
+// SHA256 hash of a file's content and its path relative to the module,
+// separated by two spaces. Lines are sorted by path, and .git directories are
+// omitted.
+type ModuleVerification struct {
+	// Digest is the expected SHA256 digest of the module's manifest, in the
+	// form sha256:<hex>.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	Digest string `json:"digest,omitempty"`
+
+	// Signature of the module's manifest.
+	// +optional
+	Signature *ModuleSignature `json:"signature,omitempty"`
+}
+
+// A ModuleSignature is a detached signature of a module's manifest.
+type ModuleSignature struct {
+	// Signature is the base64 encoded signature. ECDSA and RSA (PKCS #1 v1.5)
+	// signatures must be of the SHA256 hash of the manifest, while Ed25519
+	// signatures must be of the manifest itself.
+	Signature string `json:"signature"`
+
+	// PublicKeysSecretRef references a Secret in the Workspace's namespace.
+	// Each of its values must contain one or more PEM encoded public keys. The
+	// signature must be valid for at least one of them.
+	PublicKeysSecretRef xpv1.LocalSecretReference `json:"publicKeysSecretRef"`
+}
+
 // WorkspaceParameters are the configurable fields of a Workspace.
 type WorkspaceParameters struct {
 	// The root module of this workspace; i.e. the module containing its main.tf
 	// file. When the workspace's source is 'Remote' (the default) this can be
 	// any address supported by terraform init -from-module, for example a git
 	// repository or an S3 bucket. When the workspace's source is 'Inline' the
-	// content of a simple main.tf or main.tf.json file may be written inline.
+	// content of a simple main.tf or main.tf.json file may be written inline,
+	// or omitted in favour of InlineFiles.
 	// When the workspace's source is 'Flux', use the FluxSourceKind::namespace/name format.
+	// Supported kinds are GitRepository, OCIRepository, Bucket and ExternalArtifact.
 	// Example:
 	// Module: "GitRepository::my-namespace/my-repo"
-	Module string `json:"module"`
+	// When the workspace's source is 'OCI', use a reference to an OCI artifact
+	// containing the module, optionally pinned to a digest.
+	// Example:
+	// Module: "registry.example.org/modules/vpc:1.0.0"
+	// When the workspace's source is 'ConfigMap' the module is read from
+	// ModuleConfigMaps, and this field is ignored.
+	// +optional
+	Module string `json:"module,omitempty"`
 
 	// Specifies the format of the inline Terraform content
 	// if Source is 'Inline'
 	InlineFormat FileFormat `json:"inlineFormat,omitempty"`
 
+	// InlineFiles are written to the root module if Source is 'Inline', keyed
+	// by their path relative to the module. For example 'variables.tf',
+	// 'modules/network/main.tf', or 'templates/user-data.tftpl'. Paths may not
+	// be absolute or refer to a location outside of the module.
+	// +optional
+	InlineFiles map[string]string `json:"inlineFiles,omitempty"`
+
 	// Source of the root module of this workspace.
 	Source ModuleSource `json:"source"`
 
+	// ModuleConfigMaps from which the module is read if Source is
+	// 'ConfigMap'. Each key of a ConfigMap's data is written to a file of that
+	// name. Each key of its binary data that ends in .tar.gz or .tgz is
+	// extracted as a gzipped tarball, while any other key is written to a file
+	// of that name.
+	// +optional
+	ModuleConfigMaps []ModuleConfigMap `json:"moduleConfigMaps,omitempty"`
+
+	// Verification of the module's content before it is used. Only supported
+	// if Source is 'Remote' or 'Flux'. Terraform is not run unless
+	// verification succeeds.
+	// +optional
+	Verification *ModuleVerification `json:"verification,omitempty"`
+
 	// Entrypoint for `terraform init` within the module
 	// +kubebuilder:default=""
 	// +optional
@@ -157,12 +271,153 @@ type WorkspaceParameters struct {
 	// Boolean value to indicate  CLI logging of terraform execution is enabled or not
 	// +optional
 	EnableTerraformCLILogging bool `json:"enableTerraformCLILogging,omitempty"`
+
+	// EnableSensitiveOutputHashes publishes the names of sensitive outputs to
+	// status.atProvider.sensitiveOutputs, along with a hash of their values.
+	// This allows changes to sensitive outputs to be detected without exposing
+	// their values.
+	// +optional
+	EnableSensitiveOutputHashes bool `json:"enableSensitiveOutputHashes,omitempty"`
+
+	// ConnectionDetailsMapping selects the outputs that are published as
+	// connection details, and the keys they are published under. All outputs
+	// are published under their own names when omitted.
+	// +optional
+	ConnectionDetailsMapping []OutputMapping `json:"connectionDetailsMapping,omitempty"`
+
+	// OutputsConfigMap publishes non-sensitive Terraform outputs to a
+	// ConfigMap in the Workspace's namespace, so that they may be consumed without
+	// reading the connection Secret.
+	// +optional
+	OutputsConfigMap *OutputsConfigMap `json:"outputsConfigMap,omitempty"`
+
+	// PauseOperations skips terraform plan, apply and destroy, while the
+	// workspace's outputs and resources are still read from its state and
+	// published. Unlike the crossplane.io/paused annotation, which stops
+	// reconciliation entirely, connection details keep being refreshed. A
+	// workspace that is deleted while its operations are paused isn't
+	// destroyed until they resume. The workspace isn't ready while its
+	// operations are paused.
+	// +optional
+	PauseOperations bool `json:"pauseOperations,omitempty"`
+
+	// Timeouts of this workspace's Terraform operations. Each overrides the
+	// provider's default timeout for that operation. Operations are also
+	// bounded by the provider's overall reconcile timeout.
+	// +optional
+	Timeouts *Timeouts `json:"timeouts,omitempty"`
+
+	// Priority of this workspace. When more workspaces are waiting to be
+	// reconciled than the provider can reconcile at once, those with a
+	// higher priority are reconciled first. Workspaces of equal priority are
+	// reconciled in the order they became ready to be reconciled. Negative
+	// priorities may be used for bulk workspaces. The default is 0.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// Execution configures where Terraform plans, applies and destroys this
+	// workspace. Terraform runs in the provider process by default.
+	// +optional
+	Execution *Execution `json:"execution,omitempty"`
+}
+
+// Timeouts bound how long Terraform operations may run.
+type Timeouts struct {
+	// Init bounds how long terraform init may run.
+	// +optional
+	Init *metav1.Duration `json:"init,omitempty"`
+
+	// Plan bounds how long terraform plan may run.
+	// +optional
+	Plan *metav1.Duration `json:"plan,omitempty"`
+
+	// Apply bounds how long terraform apply may run.
+	// +optional
+	Apply *metav1.Duration `json:"apply,omitempty"`
+
+	// Destroy bounds how long terraform destroy may run.
+	// +optional
+	Destroy *metav1.Duration `json:"destroy,omitempty"`
+}
+
+// An ExecutionMode determines where Terraform runs.
+// +kubebuilder:validation:Enum=Local;Job
+type ExecutionMode string
+
+// Execution modes.
+const (
+	// ExecutionModeLocal runs Terraform in the provider process.
+	ExecutionModeLocal ExecutionMode = "Local"
+
+	// ExecutionModeJob runs Terraform in a Kubernetes Job per plan, apply
+	// or destroy. The provider must be configured to run Jobs.
+	ExecutionModeJob ExecutionMode = "Job"
+)
+
+// Execution configures where Terraform runs.
+type Execution struct {
+	// Mode determines where Terraform plans, applies and destroys the
+	// workspace. Other Terraform commands always run in the provider process.
+	// +kubebuilder:default=Local
+	// +optional
+	Mode ExecutionMode `json:"mode,omitempty"`
+
+	// Job configures the pods of the Jobs that run Terraform if Mode is
+	// 'Job'.
+	// +optional
+	Job *JobExecution `json:"job,omitempty"`
+}
+
+// JobExecution configures the pods of the Jobs that run Terraform.
+type JobExecution struct {
+	// ServiceAccountName of the pods. Terraform may use it to authenticate
+	// to cloud providers, for example using workload identity. The provider
+	// must be configured to allow Jobs to run as the service account. The
+	// default service account of the Jobs' namespace is used if it is
+	// omitted.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Resources of the container that runs Terraform.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector of the pods.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations of the pods.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 }
 
 // WorkspaceObservation are the observable fields of a Workspace.
 type WorkspaceObservation struct {
 	Checksum string                       `json:"checksum,omitempty"`
 	Outputs  map[string]extensionsV1.JSON `json:"outputs,omitempty"`
+
+	// ModuleDigest is the digest of the module that was last fetched, for
+	// sources that support content addressing. For example the digest of an
+	// OCI manifest or of a Flux artifact.
+	ModuleDigest string `json:"moduleDigest,omitempty"`
+
+	// ModuleRevision is the revision of the module that was last fetched, for
+	// sources that support it. For example the commit of a git repository, or
+	// the revision of a Flux artifact.
+	ModuleRevision string `json:"moduleRevision,omitempty"`
+
+	// DiskUsageBytes is the size in bytes of the Workspace's working
+	// directories, excluding providers installed from the shared plugin cache.
+	DiskUsageBytes int64 `json:"diskUsageBytes,omitempty"`
+
+	// SensitiveOutputs maps the names of sensitive outputs to a hex encoded
+	// HMAC-SHA256 of their JSON encoded values, keyed by the UID of the
+	// Workspace. Only published if enableSensitiveOutputHashes is true.
+	SensitiveOutputs map[string]string `json:"sensitiveOutputs,omitempty"`
+
+	// OutputsConfigMap is the ConfigMap to which outputs were last published.
+	// It is deleted when the Workspace stops publishing outputs to it.
+	OutputsConfigMap *PublishedConfigMap `json:"outputsConfigMap,omitempty"`
 }
 
 // A WorkspaceSpec defines the desired state of a Workspace.
@@ -41,10 +41,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
@@ -59,6 +61,11 @@ import (
 	clusterworkspace "github.com/upbound/provider-terraform/internal/controller/cluster"
 	namespacedworkspace "github.com/upbound/provider-terraform/internal/controller/namespaced"
 	"github.com/upbound/provider-terraform/internal/features"
+	"github.com/upbound/provider-terraform/internal/job"
+	"github.com/upbound/provider-terraform/internal/modcache"
+	"github.com/upbound/provider-terraform/internal/options"
+	"github.com/upbound/provider-terraform/internal/shard"
+	"github.com/upbound/provider-terraform/internal/workdir"
 )
 
 func init() {
@@ -76,15 +83,42 @@ func main() {
 		pollInterval             = app.Flag("poll", "Poll interval controls how often an individual resource should be checked for drift.").Default("10m").Duration()
 		pollStateMetricInterval  = app.Flag("poll-state-metric", "State metric recording interval").Default("5s").Duration()
 		pollJitter               = app.Flag("poll-jitter", "If non-zero, varies the poll interval by a random amount up to plus-or-minus this value.").Default("1m").Duration()
-		timeout                  = app.Flag("timeout", "Controls how long Terraform processes may run before they are killed.").Default("20m").Duration()
+		timeout                  = app.Flag("timeout", "Controls how long a reconcile, including all of the Terraform processes it runs, may run before they are killed.").Default("20m").Duration()
+		initTimeout              = app.Flag("init-timeout", "If non-zero, how long terraform init may run. Workspaces may override it.").Default("0s").Duration()
+		planTimeout              = app.Flag("plan-timeout", "If non-zero, how long terraform plan may run. Workspaces may override it.").Default("0s").Duration()
+		applyTimeout             = app.Flag("apply-timeout", "If non-zero, how long terraform apply may run. Workspaces may override it.").Default("0s").Duration()
+		destroyTimeout           = app.Flag("destroy-timeout", "If non-zero, how long terraform destroy may run. Workspaces may override it.").Default("0s").Duration()
 		leaderElection           = app.Flag("leader-election", "Use leader election for the controller manager.").Short('l').Default("false").Envar("LEADER_ELECTION").Bool()
 		maxReconcileRate         = app.Flag("max-reconcile-rate", "The maximum number of concurrent reconciliation operations.").Default("1").Int()
 		enableManagementPolicies = app.Flag("enable-management-policies", "Enable support for Management Policies.").Default("true").Envar("ENABLE_MANAGEMENT_POLICIES").Bool()
 		enableChangeLogs         = app.Flag("enable-changelogs", "Enable support for capturing change logs during reconciliation.").Default("false").Envar("ENABLE_CHANGE_LOGS").Bool()
 		changelogsSocketPath     = app.Flag("changelogs-socket-path", "Path for changelogs socket (if enabled)").Default("/var/run/changelogs/changelogs.sock").Envar("CHANGELOGS_SOCKET_PATH").String()
 		logEncoding              = app.Flag("log-encoding", "Container logging output ending. Possible values: console, json").Default("console").Enum("console", "json")
+		moduleCacheTTL           = app.Flag("module-cache-ttl", "If non-zero, modules from Remote sources are cached and shared by Workspaces for this long before they are fetched again.").Default("0s").Duration()
+		moduleCacheMaxSize       = app.Flag("module-cache-max-size", "The size the module cache may grow to before the least recently used modules are evicted.").Default("5GiB").Bytes()
+		gcInterval               = app.Flag("gc-interval", "How often the working directories of Workspaces that no longer exist are garbage collected.").Default("1h").Duration()
+		gcDryRun                 = app.Flag("gc-dry-run", "Log the working directories that would be garbage collected, rather than removing them.").Default("false").Envar("GC_DRY_RUN").Bool()
+		diskUsageInterval        = app.Flag("disk-usage-interval", "How often the disk usage of the working directories of Workspaces is measured.").Default("1m").Duration()
+		maxWorkspaceDiskUsage    = app.Flag("max-workspace-disk-usage", "If non-zero, the size the working directories of a Workspace may grow to before it fails, excluding providers installed from the shared plugin cache.").Default("0").Bytes()
+		persistentWorkspaceDirs  = app.Flag("persistent-workspace-dirs", "Restore the working directories of Workspaces at startup, rather than fetching and initializing them again. Use when the Terraform working directory is a PersistentVolume.").Default("false").Envar("PERSISTENT_WORKSPACE_DIRS").Bool()
+		enableSharding           = app.Flag("enable-sharding", "Spread Workspaces across all provider replicas. Incompatible with leader election.").Default("false").Envar("ENABLE_SHARDING").Bool()
+		shardNamespace           = app.Flag("shard-namespace", "The namespace of the Leases that record which replicas are members of the shard group.").Default("crossplane-system").Envar("POD_NAMESPACE").String()
+		shardIdentity            = app.Flag("shard-identity", "The identity of this replica in the shard group. Defaults to the hostname.").Envar("POD_NAME").String()
+		shardKeyLabel            = app.Flag("shard-key-label", "If set, Workspaces with this label are assigned to replicas by its value rather than by their UID, so that Workspaces with the same value are reconciled by the same replica.").String()
+		shardLeaseDuration       = app.Flag("shard-lease-duration", "How long a replica remains a member of the shard group after it last renewed its Lease.").Default("30s").Duration()
+		jobImage                 = app.Flag("job-image", "The image of the Jobs that run Terraform for Workspaces that ask for it. It must contain the Terraform binary at the same path as the provider's image. Workspaces can't run Terraform in Jobs unless this is set.").Envar("JOB_IMAGE").String()
+		jobVolumeClaim           = app.Flag("job-volume-claim", "The ReadWriteMany PersistentVolumeClaim mounted at the Terraform working directory, which Jobs mount to read and write the working directories of Workspaces.").Envar("JOB_VOLUME_CLAIM").String()
+		jobNamespace             = app.Flag("job-namespace", "The namespace in which Jobs that run Terraform are created.").Default("crossplane-system").Envar("POD_NAMESPACE").String()
+		jobServiceAccounts       = app.Flag("job-service-account", "A service account in the Job namespace that Workspaces may run their Terraform Jobs as. Workspaces that ask for any other service account fail. May be repeated.").Strings()
+		checksumIgnore           = app.Flag("checksum-ignore", "Patterns of paths within a Workspace's working directory to exclude from its checksum, so that changes to them don't cause Terraform to be initialized again. May be repeated.").Strings()
 	)
 	kingpin.MustParse(app.Parse(os.Args[1:]))
+	if *enableSharding && *leaderElection {
+		kingpin.Fatalf("--enable-sharding cannot be used with --leader-election")
+	}
+	if (*jobImage == "") != (*jobVolumeClaim == "") {
+		kingpin.Fatalf("--job-image and --job-volume-claim must be used together")
+	}
 
 	var logEncoder zap.Opts
 	switch *logEncoding {
@@ -141,6 +175,8 @@ func main() {
 
 	metrics.Registry.MustRegister(metricRecorder)
 	metrics.Registry.MustRegister(stateMetrics)
+	workdirMetrics := workdir.NewMetrics()
+	metrics.Registry.MustRegister(workdirMetrics)
 
 	ctx := context.Background()
 	clusterOpts := controller.Options{
@@ -189,6 +225,92 @@ func main() {
 		namespacedOpts.ChangeLogOptions = &clo
 	}
 
+	tfDir := "/tf"
+	if d, ok := os.LookupEnv("XP_TF_DIR"); ok {
+		tfDir = d
+	}
+
+	if *persistentWorkspaceDirs {
+		// Caches aren't started until the manager is, so read live Workspaces
+		// directly from the API server. Directories must be restored before
+		// any Workspace is reconciled.
+		gc := workdir.NewGarbageCollector(mgr.GetAPIReader(), tfDir, workdir.WithLogger(log), workdir.WithMetrics(workdirMetrics), workdir.WithDryRun(*gcDryRun))
+		kingpin.FatalIfError(gc.Restore(ctx), "Cannot restore Workspace directories")
+	}
+
+	wo := options.Workspace{
+		ChecksumIgnore: *checksumIgnore,
+		Metrics:        workdirMetrics,
+		MaxDiskUsage:   int64(*maxWorkspaceDiskUsage),
+		Timeouts:       options.Timeouts{Init: *initTimeout, Plan: *planTimeout, Apply: *applyTimeout, Destroy: *destroyTimeout},
+	}
+	// Reconciles must be allowed to run for as long as their Terraform
+	// operations may.
+	if d := wo.Timeouts.Reconcile(); d > *timeout {
+		log.Info("Raising the reconcile timeout to allow for the Terraform operation timeouts", "timeout", d)
+		*timeout = d
+	}
+	gco := []workdir.GarbageCollectorOption{
+		workdir.WithLogger(log),
+		workdir.WithMetrics(workdirMetrics),
+		workdir.WithInterval(*gcInterval),
+		workdir.WithDryRun(*gcDryRun),
+	}
+	if *enableSharding {
+		id := *shardIdentity
+		if id == "" {
+			id, err = os.Hostname()
+			kingpin.FatalIfError(err, "Cannot determine shard identity")
+		}
+		// Leases are read often, so they're read directly from the API
+		// server rather than cached.
+		kube, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+		kingpin.FatalIfError(err, "Cannot create shard client")
+		wo.Shard = shard.New(kube, *shardNamespace, id,
+			shard.WithKeyLabel(*shardKeyLabel),
+			shard.WithLeaseDuration(*shardLeaseDuration),
+			shard.WithLogger(log))
+		kingpin.FatalIfError(mgr.Add(wo.Shard), "Cannot add sharder to manager")
+		// Working directories of Workspaces that moved to other replicas are
+		// garbage collected, unless all replicas share the volume that holds
+		// them. Their new owner uses them in that case.
+		if !*persistentWorkspaceDirs && *jobVolumeClaim == "" {
+			gco = append(gco, workdir.WithKeep(wo.Shard.MayOwn))
+		}
+		log.Info("Sharding enabled", "identity", id, "namespace", *shardNamespace)
+	}
+
+	if *jobImage != "" {
+		cs, err := kubernetes.NewForConfig(mgr.GetConfig())
+		kingpin.FatalIfError(err, "Cannot create Job client")
+		wo.Jobs = job.NewLauncher(cs, *jobNamespace, *jobImage, *jobVolumeClaim, tfDir, job.WithServiceAccounts(*jobServiceAccounts...), job.WithLogger(log))
+		kingpin.FatalIfError(wo.Jobs.Permitted(context.Background()), "Cannot run Terraform Jobs")
+		log.Info("Terraform Jobs enabled", "image", *jobImage, "volume-claim", *jobVolumeClaim, "namespace", *jobNamespace)
+	}
+
+	// Workspaces of both kinds share working directories, so a single garbage
+	// collector considers both kinds.
+	for _, dir := range []string{tfDir, filepath.Join("/tmp", tfDir)} {
+		gc := workdir.NewGarbageCollector(mgr.GetClient(), dir, gco...)
+		kingpin.FatalIfError(mgr.Add(manager.RunnableFunc(gc.Run)), "Cannot add working directory garbage collector to manager")
+	}
+	if *gcDryRun {
+		log.Info("Working directory garbage collection is in dry-run mode")
+	}
+	wo.DiskUsage = workdir.NewDiskUsage([]string{tfDir, filepath.Join("/tmp", tfDir)},
+		workdir.WithUsageInterval(*diskUsageInterval),
+		workdir.WithUsageLogger(log))
+	kingpin.FatalIfError(mgr.Add(manager.RunnableFunc(wo.DiskUsage.Run)), "Cannot add working directory disk usage measurer to manager")
+
+	if *moduleCacheTTL > 0 {
+		wo.ModuleCache = modcache.New(filepath.Join(tfDir, ".modules"),
+			modcache.WithTTL(*moduleCacheTTL),
+			modcache.WithMaxSize(int64(*moduleCacheMaxSize)),
+			modcache.WithLogger(log))
+		kingpin.FatalIfError(mgr.Add(manager.RunnableFunc(wo.ModuleCache.Run)), "Cannot add module cache to manager")
+		log.Info("Module cache enabled", "ttl", moduleCacheTTL.String(), "max-size", moduleCacheMaxSize.String())
+	}
+
 	canSafeStart, err := canWatchCRD(ctx, mgr)
 	kingpin.FatalIfError(err, "SafeStart precheck failed")
 	if canSafeStart {
@@ -196,12 +318,12 @@ func main() {
 		clusterOpts.Gate = crdGate
 		namespacedOpts.Gate = crdGate
 		kingpin.FatalIfError(customresourcesgate.Setup(mgr, namespacedOpts), "Cannot setup CRD gate")
-		kingpin.FatalIfError(clusterworkspace.SetupGated(mgr, clusterOpts, *timeout, *pollJitter), "Cannot setup cluster-scoped Workspace controllers")
-		kingpin.FatalIfError(namespacedworkspace.SetupGated(mgr, namespacedOpts, *timeout, *pollJitter), "Cannot setup namespaced Workspace controllers")
+		kingpin.FatalIfError(clusterworkspace.SetupGated(mgr, clusterOpts, *timeout, *pollJitter, wo), "Cannot setup cluster-scoped Workspace controllers")
+		kingpin.FatalIfError(namespacedworkspace.SetupGated(mgr, namespacedOpts, *timeout, *pollJitter, wo), "Cannot setup namespaced Workspace controllers")
 	} else {
 		log.Info("Provider has missing RBAC permissions for watching CRDs, controller SafeStart capability will be disabled")
-		kingpin.FatalIfError(clusterworkspace.Setup(mgr, clusterOpts, *timeout, *pollJitter), "Cannot setup cluster-scoped Workspace controllers")
-		kingpin.FatalIfError(namespacedworkspace.Setup(mgr, namespacedOpts, *timeout, *pollJitter), "Cannot setup namespaced Workspace controllers")
+		kingpin.FatalIfError(clusterworkspace.Setup(mgr, clusterOpts, *timeout, *pollJitter, wo), "Cannot setup cluster-scoped Workspace controllers")
+		kingpin.FatalIfError(namespacedworkspace.Setup(mgr, namespacedOpts, *timeout, *pollJitter, wo), "Cannot setup namespaced Workspace controllers")
 	}
 	kingpin.FatalIfError(mgr.Start(ctrl.SetupSignalHandler()), "Cannot start controller manager")
 }
@@ -144,5 +144,17 @@ func enrichLocalSecretRefs(pc *namespacedv1beta1.ProviderConfig, mg resource.Man
 				}
 			}
 		}
+		for i := range pc.Spec.OCIPullSecrets {
+			pc.Spec.OCIPullSecrets[i].Namespace = mg.GetNamespace()
+		}
+		if ssh := pc.Spec.GitSSH; ssh != nil {
+			ssh.PrivateKeySecretRef.Namespace = mg.GetNamespace()
+			if ssh.KnownHostsSecretRef != nil {
+				ssh.KnownHostsSecretRef.Namespace = mg.GetNamespace()
+			}
+		}
+		for i := range pc.Spec.RegistryCredentials {
+			pc.Spec.RegistryCredentials[i].TokenSecretRef.Namespace = mg.GetNamespace()
+		}
 	}
 }
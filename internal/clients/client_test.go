@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	namespacedv1beta1 "github.com/upbound/provider-terraform/apis/namespaced/v1beta1"
+)
+
+func TestEnrichLocalSecretRefs(t *testing.T) {
+	mg := &namespacedv1beta1.Workspace{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant"}}
+
+	cases := map[string]struct {
+		reason string
+		pc     *namespacedv1beta1.ProviderConfig
+		want   *namespacedv1beta1.ProviderConfig
+	}{
+		"Nil": {
+			reason: "A nil ProviderConfig should be ignored.",
+		},
+		"GitSSH": {
+			reason: "Git SSH Secrets should be read from the namespace of the Workspace, not a namespace of the tenant's choosing.",
+			pc: &namespacedv1beta1.ProviderConfig{Spec: namespacedv1beta1.ProviderConfigSpec{
+				GitSSH: &namespacedv1beta1.GitSSHCredentials{
+					PrivateKeySecretRef: xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "key"}, Key: "id_rsa"},
+					KnownHostsSecretRef: &xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "hosts"}, Key: "known_hosts"},
+				},
+			}},
+			want: &namespacedv1beta1.ProviderConfig{Spec: namespacedv1beta1.ProviderConfigSpec{
+				GitSSH: &namespacedv1beta1.GitSSHCredentials{
+					PrivateKeySecretRef: xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "tenant", Name: "key"}, Key: "id_rsa"},
+					KnownHostsSecretRef: &xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "tenant", Name: "hosts"}, Key: "known_hosts"},
+				},
+			}},
+		},
+		"RegistryCredentials": {
+			reason: "Registry token Secrets should be read from the namespace of the Workspace, not a namespace of the tenant's choosing.",
+			pc: &namespacedv1beta1.ProviderConfig{Spec: namespacedv1beta1.ProviderConfigSpec{
+				RegistryCredentials: []namespacedv1beta1.RegistryCredentials{
+					{Hostname: "app.terraform.io", TokenSecretRef: xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "token"}, Key: "token"}},
+					{Hostname: "registry.example.org", TokenSecretRef: xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Name: "token"}, Key: "token"}},
+				},
+			}},
+			want: &namespacedv1beta1.ProviderConfig{Spec: namespacedv1beta1.ProviderConfigSpec{
+				RegistryCredentials: []namespacedv1beta1.RegistryCredentials{
+					{Hostname: "app.terraform.io", TokenSecretRef: xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "tenant", Name: "token"}, Key: "token"}},
+					{Hostname: "registry.example.org", TokenSecretRef: xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "tenant", Name: "token"}, Key: "token"}},
+				},
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			enrichLocalSecretRefs(tc.pc, mg)
+			if diff := cmp.Diff(tc.want, tc.pc); diff != "" {
+				t.Errorf("\n%s\nenrichLocalSecretRefs(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
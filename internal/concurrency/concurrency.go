@@ -0,0 +1,74 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package concurrency bounds how many Terraform processes run at once for
+// the Workspaces that share a ProviderConfig.
+package concurrency
+
+import (
+	"context"
+	"sync"
+)
+
+// A Limiter bounds how many Terraform processes run at once for each of a set
+// of keys, for example for each ProviderConfig.
+type Limiter struct {
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewLimiter returns a new Limiter.
+func NewLimiter() *Limiter {
+	return &Limiter{slots: map[string]chan struct{}{}}
+}
+
+// For returns a Semaphore that allows up to the supplied number of processes
+// to run at once for the supplied key. Semaphores for the same key share their
+// slots. If the limit of a key changes, processes that are already running
+// don't count toward the new limit.
+func (l *Limiter) For(key string, limit int) *Semaphore {
+	return &Semaphore{limiter: l, key: key, limit: limit}
+}
+
+func (l *Limiter) slotsFor(key string, limit int) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch, ok := l.slots[key]
+	if !ok || cap(ch) != limit {
+		ch = make(chan struct{}, limit)
+		l.slots[key] = ch
+	}
+	return ch
+}
+
+// A Semaphore bounds how many processes run at once for a key.
+type Semaphore struct {
+	limiter *Limiter
+	key     string
+	limit   int
+}
+
+// Acquire a slot, blocking until one is free or the supplied context is done.
+// The returned function releases the slot.
+func (s *Semaphore) Acquire(ctx context.Context) (func(), error) {
+	ch := s.limiter.slotsFor(s.key, s.limit)
+	select {
+	case ch <- struct{}{}:
+		return func() { <-ch }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
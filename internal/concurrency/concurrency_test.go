@@ -0,0 +1,107 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+)
+
+func TestAcquire(t *testing.T) {
+	type want struct {
+		acquired int
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		held   map[string]int
+		key    string
+		limit  int
+		want   want
+	}{
+		"SlotFree": {
+			reason: "We should acquire a slot if fewer than the limit are held.",
+			held:   map[string]int{"a": 1},
+			key:    "a",
+			limit:  2,
+			want:   want{acquired: 1},
+		},
+		"SlotsFull": {
+			reason: "We should wait for a slot until the context is done if all are held.",
+			held:   map[string]int{"a": 2},
+			key:    "a",
+			limit:  2,
+			want:   want{err: context.DeadlineExceeded},
+		},
+		"OtherKey": {
+			reason: "Slots held for other keys should not count toward the limit.",
+			held:   map[string]int{"b": 2},
+			key:    "a",
+			limit:  2,
+			want:   want{acquired: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			l := NewLimiter()
+			for k, n := range tc.held {
+				for range n {
+					if _, err := l.For(k, tc.limit).Acquire(context.Background()); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			acquired := 0
+			release, err := l.For(tc.key, tc.limit).Acquire(ctx)
+			if err == nil {
+				acquired++
+				release()
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ns.Acquire(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.acquired, acquired); diff != "" {
+				t.Errorf("\n%s\ns.Acquire(...): -want acquired, +got acquired:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	s := NewLimiter().For("a", 1)
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := s.Acquire(ctx); err != nil {
+		t.Errorf("s.Acquire(...): want a released slot to be acquired, got error: %v", err)
+	}
+}
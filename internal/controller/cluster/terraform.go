@@ -25,32 +25,23 @@ import (
 
 	"github.com/upbound/provider-terraform/internal/controller/cluster/config"
 	"github.com/upbound/provider-terraform/internal/controller/cluster/workspace"
+	"github.com/upbound/provider-terraform/internal/options"
 )
 
 // Setup creates all TF controllers with the supplied logger and adds them
 // to the supplied manager.
-func Setup(mgr ctrl.Manager, o controller.Options, timeout time.Duration, pollJitter time.Duration) error {
-	for _, setup := range []func(ctrl.Manager, controller.Options, time.Duration, time.Duration) error{
-		config.Setup,
-		workspace.Setup,
-	} {
-		if err := setup(mgr, o, timeout, pollJitter); err != nil {
-			return err
-		}
+func Setup(mgr ctrl.Manager, o controller.Options, timeout time.Duration, pollJitter time.Duration, wo options.Workspace) error {
+	if err := config.Setup(mgr, o, timeout, pollJitter); err != nil {
+		return err
 	}
-	return nil
+	return workspace.Setup(mgr, o, timeout, pollJitter, wo)
 }
 
 // SetupGated creates all controllers with the supplied logger and adds them to
 // the supplied manager gated.
-func SetupGated(mgr ctrl.Manager, o controller.Options, timeout time.Duration, pollJitter time.Duration) error {
-	for _, setup := range []func(ctrl.Manager, controller.Options, time.Duration, time.Duration) error{
-		config.SetupGated,
-		workspace.SetupGated,
-	} {
-		if err := setup(mgr, o, timeout, pollJitter); err != nil {
-			return err
-		}
+func SetupGated(mgr ctrl.Manager, o controller.Options, timeout time.Duration, pollJitter time.Duration, wo options.Workspace) error {
+	if err := config.SetupGated(mgr, o, timeout, pollJitter); err != nil {
+		return err
 	}
-	return nil
+	return workspace.SetupGated(mgr, o, timeout, pollJitter, wo)
 }
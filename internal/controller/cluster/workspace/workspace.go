@@ -18,11 +18,15 @@ package workspace
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -33,9 +37,15 @@ import (
 	"github.com/spf13/afero"
 	corev1 "k8s.io/api/core/v1"
 	extensionsV1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kmeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
@@ -47,12 +57,22 @@ import (
 
 	"github.com/upbound/provider-terraform/apis/cluster/v1beta1"
 	tfClient "github.com/upbound/provider-terraform/internal/clients"
+	"github.com/upbound/provider-terraform/internal/concurrency"
 	"github.com/upbound/provider-terraform/internal/features"
+	"github.com/upbound/provider-terraform/internal/flux"
+	"github.com/upbound/provider-terraform/internal/gitauth"
+	"github.com/upbound/provider-terraform/internal/job"
+	"github.com/upbound/provider-terraform/internal/modcache"
+	"github.com/upbound/provider-terraform/internal/oci"
+	"github.com/upbound/provider-terraform/internal/options"
+	"github.com/upbound/provider-terraform/internal/priority"
+	"github.com/upbound/provider-terraform/internal/revision"
+	"github.com/upbound/provider-terraform/internal/shard"
 	"github.com/upbound/provider-terraform/internal/terraform"
+	"github.com/upbound/provider-terraform/internal/verify"
 	"github.com/upbound/provider-terraform/internal/workdir"
 
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
-	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
 )
 
 const (
@@ -61,28 +81,54 @@ const (
 	errGetPC        = "cannot get ProviderConfig"
 	errGetCreds     = "cannot get credentials"
 
-	errMkdir              = "cannot make Terraform configuration directory"
-	errRemoteModule       = "cannot get remote Terraform module"
-	errFluxArtefactModule = "cannot get Flux Artefact Terraform module"
-	errSetGitCredDir      = "cannot set GIT_CRED_DIR environment variable"
-	errWriteCreds         = "cannot write Terraform credentials"
-	errWriteGitCreds      = "cannot write .git-credentials to /tmp dir"
-	errWriteConfig        = "cannot write Terraform configuration " + tfConfig
-	errWriteMain          = "cannot write Terraform configuration "
-	errWriteBackend       = "cannot write Terraform configuration " + tfBackendFile
-	errInit               = "cannot initialize Terraform configuration"
-	errWorkspace          = "cannot select Terraform workspace"
-	errResources          = "cannot list Terraform resources"
-	errDiff               = "cannot diff (i.e. plan) Terraform configuration"
-	errOutputs            = "cannot list Terraform outputs"
-	errOptions            = "cannot determine Terraform options"
-	errApply              = "cannot apply Terraform configuration"
-	errDestroy            = "cannot destroy Terraform configuration"
-	errVarFile            = "cannot get tfvars"
-	errVarMap             = "cannot get tfvars from var map"
-	errVarResolution      = "cannot resolve variables"
-	errDeleteWorkspace    = "cannot delete Terraform workspace"
-	errChecksum           = "cannot calculate workspace checksum"
+	errMkdir                = "cannot make Terraform configuration directory"
+	errRemoteModule         = "cannot get remote Terraform module"
+	errFluxArtefactModule   = "cannot get Flux Artefact Terraform module"
+	errGetFluxSourceMapping = "cannot determine whether Flux sources are installed"
+	errIndexConfigMaps      = "cannot index Workspaces by the ConfigMaps they read their module from"
+	errIndexFluxSources     = "cannot index Workspaces by the Flux sources they read their module from"
+	errOCIModule            = "cannot get OCI artifact Terraform module"
+	errPruneModule          = "cannot remove files that are no longer part of the Terraform module"
+	errGetPullSecret        = "cannot get OCI pull secret"
+	errGetModuleConfigMap   = "cannot get module ConfigMap"
+	errWriteModuleConfigMap = "cannot write module ConfigMap"
+	errVerifyModule         = "cannot verify Terraform module"
+	errCopyModule           = "cannot copy verified Terraform module"
+	errNoVerification       = "module verification requires a digest or a signature"
+	errDecodeSignature      = "cannot decode module signature"
+	errGetPublicKeys        = "cannot get module signature public keys"
+	errWriteModuleRevision  = "cannot write Terraform module revision"
+	errWriteCreds           = "cannot write Terraform credentials"
+	errWriteGitCreds        = "cannot write .git-credentials to /tmp dir"
+	errGetGitSSHCreds       = "cannot get git SSH credentials"
+	errGetRegistryToken     = "cannot get Terraform registry token"
+	errWriteConfig          = "cannot write Terraform configuration " + tfConfig
+	errWriteMain            = "cannot write Terraform configuration "
+	errWriteInlineFiles     = "cannot write inline Terraform module files"
+	errWriteBackend         = "cannot write Terraform configuration " + tfBackendFile
+	errInit                 = "cannot initialize Terraform configuration"
+	errWorkspace            = "cannot select Terraform workspace"
+	errResources            = "cannot list Terraform resources"
+	errDiff                 = "cannot diff (i.e. plan) Terraform configuration"
+	errOutputs              = "cannot list Terraform outputs"
+	errOptions              = "cannot determine Terraform options"
+	errApply                = "cannot apply Terraform configuration"
+	errDestroy              = "cannot destroy Terraform configuration"
+	errVarFile              = "cannot get tfvars"
+	errVarMap               = "cannot get tfvars from var map"
+	errVarResolution        = "cannot resolve variables"
+	errDeleteWorkspace      = "cannot delete Terraform workspace"
+	errChecksum             = "cannot calculate workspace checksum"
+	errDiskUsage            = "cannot determine workspace disk usage"
+	errJobsDisabled         = "cannot run Terraform in a Job: the provider is not configured to run Jobs"
+	errFmtTimeout           = "terraform %s timed out after %s"
+	errFmtReconcileTimeout  = "terraform init, plan and apply or destroy may take %s, which exceeds the provider's reconcile timeout of %s; raise the provider's --timeout"
+	errOperationsPaused     = "Terraform operations are paused"
+	errFmtDiskQuota         = "working directories use %d bytes, which exceeds the maximum of %d bytes"
+	errPublishOutputs       = "cannot publish Terraform outputs to ConfigMap"
+	errGetStaleOutputs      = "cannot get ConfigMap to which Terraform outputs were previously published"
+	errDeleteStaleOutputs   = "cannot delete ConfigMap to which Terraform outputs were previously published"
+	errFmtInvalidOutputKeys = "outputs were not published under keys that are not valid ConfigMap or Secret keys: %s"
 
 	gitCredentialsFilename = ".git-credentials"
 )
@@ -94,6 +140,20 @@ const (
 	tfMainJSON    = "main.tf.json"
 	tfConfig      = "crossplane-provider-config.tf"
 	tfBackendFile = "crossplane.remote.tfbackend"
+
+	// moduleConfigMapsField indexes Workspaces by the ConfigMaps they read
+	// their module from, so that only the Workspaces that read their module
+	// from a ConfigMap are listed when it changes.
+	moduleConfigMapsField = "spec.forProvider.moduleConfigMaps"
+
+	// fluxSourceField indexes Workspaces by the Flux source they read their
+	// module from, so that only the Workspaces that read their module from a
+	// Flux source are listed when it changes.
+	fluxSourceField = "spec.forProvider.fluxSource"
+
+	// tfModuleRevision records the source and revision of the last fetched
+	// Remote module, or the source and digest of the last pulled OCI module.
+	tfModuleRevision = ".crossplane-module-revision"
 )
 
 func envVarFallback(envvar string, fallback string) string {
@@ -118,23 +178,33 @@ type tfclient interface {
 }
 
 // Setup adds a controller that reconciles Workspace managed resources.
-func Setup(mgr ctrl.Manager, o controller.Options, timeout, pollJitter time.Duration) error {
+func Setup(mgr ctrl.Manager, o controller.Options, timeout, pollJitter time.Duration, wo options.Workspace) error {
 	name := managed.ControllerName(v1beta1.WorkspaceGroupKind)
 
 	fs := afero.Afero{Fs: afero.NewOsFs()}
-	gcWorkspace := workdir.NewGarbageCollector(mgr.GetClient(), tfDir, workdir.WithFs(fs), workdir.WithLogger(o.Logger))
-	go gcWorkspace.Run(context.TODO(), false)
-
-	gcTmp := workdir.NewGarbageCollector(mgr.GetClient(), filepath.Join("/tmp", tfDir), workdir.WithFs(fs), workdir.WithLogger(o.Logger))
-	go gcTmp.Run(context.TODO(), false)
+	// The module revision and the manifest of module files are recorded by
+	// Connect after the module is fetched, and never require Terraform to be
+	// initialized again.
+	ignore := append([]string{tfModuleRevision, workdir.ManifestFile}, wo.ChecksumIgnore...)
 
 	c := &connector{
-		kube:   mgr.GetClient(),
-		usage:  resource.NewLegacyProviderConfigUsageTracker(mgr.GetClient(), &v1beta1.ProviderConfigUsage{}),
-		logger: o.Logger,
-		fs:     fs,
-		terraform: func(dir string, usePluginCache bool, enableTerraformCLILogging bool, logger logging.Logger, envs ...string) tfclient {
-			return terraform.Harness{Path: tfPath, Dir: dir, UsePluginCache: usePluginCache, EnableTerraformCLILogging: enableTerraformCLILogging, Logger: logger, Envs: envs}
+		kube:         mgr.GetClient(),
+		usage:        resource.NewLegacyProviderConfigUsageTracker(mgr.GetClient(), &v1beta1.ProviderConfigUsage{}),
+		logger:       o.Logger,
+		fs:           fs,
+		moduleCache:  wo.ModuleCache,
+		revisions:    revision.NewResolver(),
+		metrics:      wo.Metrics,
+		maxDiskUsage: wo.MaxDiskUsage,
+		diskUsage:    wo.DiskUsage,
+		jobs:         wo.Jobs,
+		limits:       concurrency.NewLimiter(),
+		timeouts:     wo.Timeouts,
+		// The Workspace's operations can't run for longer than the
+		// reconcile that runs them.
+		reconcileTimeout: timeout,
+		terraform: func(dir string, usePluginCache bool, enableTerraformCLILogging bool, logger logging.Logger, runner terraform.Runner, limiter terraform.Limiter, envs ...string) tfclient {
+			return terraform.Harness{Path: tfPath, Dir: dir, UsePluginCache: usePluginCache, EnableTerraformCLILogging: enableTerraformCLILogging, Logger: logger, Envs: envs, ChecksumIgnore: ignore, Runner: runner, Limiter: limiter}
 		},
 	}
 
@@ -161,19 +231,153 @@ func Setup(mgr ctrl.Manager, o controller.Options, timeout, pollJitter time.Dura
 		resource.ManagedKind(v1beta1.WorkspaceGroupVersionKind),
 		opts...)
 
-	return ctrl.NewControllerManagedBy(mgr).
+	// Workspaces with a higher priority are dequeued first when every
+	// worker is busy.
+	co := o.ForControllerRuntime()
+	co.NewQueue = priority.NewQueue(workspacePriority(mgr.GetClient()))
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1beta1.Workspace{}, moduleConfigMapsField, indexModuleConfigMaps); err != nil {
+		return errors.Wrap(err, errIndexConfigMaps)
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1beta1.Workspace{}, fluxSourceField, indexFluxSource); err != nil {
+		return errors.Wrap(err, errIndexFluxSources)
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		Named(name).
-		WithOptions(o.ForControllerRuntime()).
-		WithEventFilter(resource.DesiredStateChanged()).
-		For(&v1beta1.Workspace{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+		WithOptions(co).
+		For(&v1beta1.Workspace{}, builder.WithPredicates(resource.DesiredStateChanged())).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(moduleConfigMapWorkspaces(mgr.GetClient())))
+
+	// Flux sources are only watched if their CRDs are installed, so that the
+	// provider can run without Flux.
+	for _, k := range flux.Kinds {
+		gvk := flux.GroupVersionKind(k)
+		if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			if kmeta.IsNoMatchError(err) {
+				continue
+			}
+			return errors.Wrap(err, errGetFluxSourceMapping)
+		}
+		src, err := flux.New(k)
+		if err != nil {
+			return err
+		}
+		b = b.Watches(src, handler.EnqueueRequestsFromMapFunc(fluxSourceWorkspaces(mgr.GetClient(), k)))
+	}
+
+	var rec reconcile.Reconciler = ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter)
+	if wo.Shard != nil {
+		// Each replica only reconciles the Workspaces it owns, and
+		// reconsiders all Workspaces when replicas join or leave.
+		rec = shard.NewReconciler(rec, mgr.GetClient(), wo.Shard, func() client.Object { return &v1beta1.Workspace{} })
+		b = b.WatchesRawSource(wo.Shard.Source(listWorkspaces(mgr.GetClient())))
+	}
+
+	return b.Complete(rec)
+}
+
+// workspacePriority returns a function that returns the priority of the
+// Workspace a request is for. Workspaces that can't be read have the default
+// priority.
+func workspacePriority(kube client.Reader) priority.Func {
+	return func(r reconcile.Request) int32 {
+		ws := &v1beta1.Workspace{}
+		if err := kube.Get(context.Background(), r.NamespacedName, ws); err != nil {
+			return 0
+		}
+		return ws.Spec.ForProvider.Priority
+	}
+}
+
+// listWorkspaces returns a function that lists all Workspaces.
+func listWorkspaces(kube client.Reader) func(ctx context.Context) ([]client.Object, error) {
+	return func(ctx context.Context) ([]client.Object, error) {
+		l := &v1beta1.WorkspaceList{}
+		if err := kube.List(ctx, l); err != nil {
+			return nil, err
+		}
+		objs := make([]client.Object, 0, len(l.Items))
+		for i := range l.Items {
+			objs = append(objs, &l.Items[i])
+		}
+		return objs, nil
+	}
+}
+
+// indexModuleConfigMaps returns the namespaced names of the ConfigMaps the
+// supplied Workspace reads its module from.
+func indexModuleConfigMaps(o client.Object) []string {
+	ws, ok := o.(*v1beta1.Workspace)
+	if !ok || ws.Spec.ForProvider.Source != v1beta1.ModuleSourceConfigMap {
+		return nil
+	}
+	keys := make([]string, 0, len(ws.Spec.ForProvider.ModuleConfigMaps))
+	for _, ref := range ws.Spec.ForProvider.ModuleConfigMaps {
+		keys = append(keys, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}.String())
+	}
+	return keys
+}
+
+// moduleConfigMapWorkspaces returns a function that maps a ConfigMap to the
+// Workspaces that read their module from it.
+func moduleConfigMapWorkspaces(kube client.Reader) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		l := &v1beta1.WorkspaceList{}
+		key := types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}.String()
+		if err := kube.List(ctx, l, client.MatchingFields{moduleConfigMapsField: key}); err != nil {
+			return nil
+		}
+		reqs := make([]reconcile.Request, 0, len(l.Items))
+		for _, ws := range l.Items {
+			reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: ws.GetNamespace(), Name: ws.GetName()}})
+		}
+		return reqs
+	}
+}
+
+// indexFluxSource returns the kind and namespaced name of the Flux source the
+// supplied Workspace reads its module from.
+func indexFluxSource(o client.Object) []string {
+	ws, ok := o.(*v1beta1.Workspace)
+	if !ok || ws.Spec.ForProvider.Source != v1beta1.ModuleSourceFlux {
+		return nil
+	}
+	src, err := flux.ParseSource(ws.Spec.ForProvider.Module)
+	if err != nil {
+		return nil
+	}
+	return []string{fluxSourceKey(src.Kind, types.NamespacedName{Namespace: src.Namespace, Name: src.Name})}
+}
+
+// fluxSourceKey returns the key Workspaces that read their module from the
+// supplied Flux source are indexed by.
+func fluxSourceKey(kind string, nn types.NamespacedName) string {
+	return kind + "/" + nn.String()
+}
+
+// fluxSourceWorkspaces returns a function that maps a Flux source of the
+// supplied kind to the Workspaces that read their module from it.
+func fluxSourceWorkspaces(kube client.Reader, kind string) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		l := &v1beta1.WorkspaceList{}
+		key := fluxSourceKey(kind, types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()})
+		if err := kube.List(ctx, l, client.MatchingFields{fluxSourceField: key}); err != nil {
+			return nil
+		}
+		reqs := make([]reconcile.Request, 0, len(l.Items))
+		for _, ws := range l.Items {
+			reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: ws.GetNamespace(), Name: ws.GetName()}})
+		}
+		return reqs
+	}
 }
 
 // SetupGated adds a controller that reconciles ProviderConfigs by accounting for
 // their current usage.
-func SetupGated(mgr ctrl.Manager, o controller.Options, timeout time.Duration, pollJitter time.Duration) error {
+func SetupGated(mgr ctrl.Manager, o controller.Options, timeout time.Duration, pollJitter time.Duration, wo options.Workspace) error {
 	o.Gate.Register(func() {
-		if err := Setup(mgr, o, timeout, pollJitter); err != nil {
+		if err := Setup(mgr, o, timeout, pollJitter, wo); err != nil {
 			mgr.GetLogger().Error(err, "unable to setup reconciler", "gvk", v1beta1.WorkspaceGroupVersionKind.String())
 		}
 	}, v1beta1.WorkspaceGroupVersionKind)
@@ -181,11 +385,44 @@ func SetupGated(mgr ctrl.Manager, o controller.Options, timeout time.Duration, p
 }
 
 type connector struct {
-	kube      client.Client
-	usage     tfClient.LegacyTracker
-	logger    logging.Logger
-	fs        afero.Afero
-	terraform func(dir string, usePluginCache bool, enableTerraformCLILogging bool, logger logging.Logger, envs ...string) tfclient
+	kube   client.Client
+	usage  tfClient.LegacyTracker
+	logger logging.Logger
+	fs     afero.Afero
+
+	// moduleCache caches modules from Remote sources, if it is not nil.
+	moduleCache *modcache.Cache
+
+	// revisions resolves the revisions of Remote sources, if it is not nil.
+	revisions *revision.Resolver
+
+	// metrics records the disk usage of working directories, if it is not
+	// nil.
+	metrics *workdir.Metrics
+
+	// maxDiskUsage is the size in bytes the working directories of a
+	// Workspace may grow to, if it is greater than zero.
+	maxDiskUsage int64
+
+	// diskUsage periodically measures the disk usage of working directories,
+	// if it is not nil.
+	diskUsage *workdir.DiskUsage
+
+	// jobs runs Terraform in Kubernetes Jobs, if it is not nil.
+	jobs *job.Launcher
+
+	// limits bounds how many Terraform processes run at once for each
+	// ProviderConfig, if it is not nil.
+	limits *concurrency.Limiter
+
+	// timeouts are the default timeouts of Terraform operations.
+	timeouts options.Timeouts
+
+	// reconcileTimeout bounds how long a reconcile, including all of its
+	// Terraform operations, may run, if it is greater than zero.
+	reconcileTimeout time.Duration
+
+	terraform func(dir string, usePluginCache bool, enableTerraformCLILogging bool, logger logging.Logger, runner terraform.Runner, limiter terraform.Limiter, envs ...string) tfclient
 }
 
 func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) { //nolint:gocyclo
@@ -197,9 +434,13 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 	if !ok {
 		return nil, errors.New(errNotWorkspace)
 	}
+	timeouts := operationTimeouts(c.timeouts, cr.Spec.ForProvider.Timeouts)
+	if d := timeouts.Reconcile(); c.reconcileTimeout > 0 && d > c.reconcileTimeout {
+		return nil, errors.Errorf(errFmtReconcileTimeout, d, c.reconcileTimeout)
+	}
 	l := c.logger.WithValues("request", map[string]string{"name": cr.Name})
 	// NOTE(negz): This directory will be garbage collected by the workdir
-	// garbage collector that is started in Setup.
+	// garbage collector that is started by the provider's main function.
 	dir := filepath.Join(tfDir, string(cr.GetUID()))
 	if err := c.fs.MkdirAll(dir, 0700); resource.Ignore(os.IsExist, err) != nil {
 		return nil, errors.Wrap(err, errMkdir)
@@ -208,12 +449,18 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return nil, errors.Wrap(err, errMkdir)
 	}
 
+	workDirs := []string{dir, filepath.Clean(filepath.Join("/tmp", dir))}
+
 	pc, err := tfClient.ResolveProviderConfig(ctx, c.kube, c.usage, nil, mg)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to resolve provider config")
 	}
 
-	// Make git credentials available to inline and remote sources
+	// Make git credentials available to inline and remote sources. They're
+	// passed to each git and Terraform process, rather than configured for
+	// the provider process, so that Workspaces using different credentials
+	// may be reconciled concurrently.
+	var gitCreds gitauth.Credentials
 	for _, cd := range pc.Spec.Credentials {
 		if cd.Filename != gitCredentialsFilename {
 			continue
@@ -222,55 +469,105 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		if err != nil {
 			return nil, errors.Wrap(err, errGetCreds)
 		}
-		// NOTE(bobh66): Put the git credentials file in /tmp/tf/<UUID> so it doesn't get removed or overwritten
-		// by the remote module source case
-		gitCredDir := filepath.Clean(filepath.Join("/tmp", dir))
-		if err = c.fs.MkdirAll(gitCredDir, 0700); err != nil {
-			return nil, errors.Wrap(err, errWriteGitCreds)
-		}
-
-		// NOTE(ytsarev): Make go-getter pick up .git-credentials, see /.gitconfig in the container image
-		err = os.Setenv("GIT_CRED_DIR", gitCredDir)
+		gitCreds.Store = data
+	}
+	if ssh := pc.Spec.GitSSH; ssh != nil {
+		key, err := resource.CommonCredentialExtractor(ctx, xpv1.CredentialsSourceSecret, c.kube, xpv1.CommonCredentialSelectors{SecretRef: &ssh.PrivateKeySecretRef})
 		if err != nil {
-			return nil, errors.Wrap(err, errSetGitCredDir)
+			return nil, errors.Wrap(err, errGetGitSSHCreds)
 		}
-		p := filepath.Clean(filepath.Join(gitCredDir, filepath.Base(cd.Filename)))
-		if err := c.fs.WriteFile(p, data, 0600); err != nil {
-			return nil, errors.Wrap(err, errWriteGitCreds)
+		gitCreds.SSHPrivateKey = key
+		if ssh.KnownHostsSecretRef != nil {
+			hosts, err := resource.CommonCredentialExtractor(ctx, xpv1.CredentialsSourceSecret, c.kube, xpv1.CommonCredentialSelectors{SecretRef: ssh.KnownHostsSecretRef})
+			if err != nil {
+				return nil, errors.Wrap(err, errGetGitSSHCreds)
+			}
+			gitCreds.SSHKnownHosts = hosts
+		}
+	}
+	// NOTE(bobh66): Put the git credentials file in /tmp/tf/<UUID> so it doesn't get removed or overwritten
+	// by the remote module source case
+	gitEnv, err := gitauth.Write(c.fs, filepath.Clean(filepath.Join("/tmp", dir)), gitCreds)
+	if err != nil {
+		return nil, errors.Wrap(err, errWriteGitCreds)
+	}
+
+	// Modules that must be verified are fetched to an empty
+	// directory, so that they can't be confused with files written by earlier
+	// reconciles. They're only copied into the work dir once verified.
+	moduleDir := dir
+	verifyModule := cr.Spec.ForProvider.Verification != nil && (cr.Spec.ForProvider.Source == v1beta1.ModuleSourceRemote || cr.Spec.ForProvider.Source == v1beta1.ModuleSourceFlux)
+	if verifyModule {
+		moduleDir = filepath.Join("/tmp", dir, "module")
+		if err := c.fs.RemoveAll(moduleDir); err != nil {
+			return nil, errors.Wrap(err, errMkdir)
 		}
 	}
 
+	var digest, rev string
 	switch cr.Spec.ForProvider.Source {
 	case v1beta1.ModuleSourceRemote:
-		gc := getter.Client{
-			Src: cr.Spec.ForProvider.Module,
-			Dst: dir,
-			Pwd: dir,
-
-			Mode: getter.ClientModeDir,
+		rev = c.resolveRevision(ctx, l, cr.Spec.ForProvider.Module, dir, gitEnv)
+		fetched := cr.Spec.ForProvider.Module + "@" + rev
+		if rev != "" && !verifyModule {
+			if b, err := c.fs.ReadFile(filepath.Join(dir, tfModuleRevision)); err == nil && string(b) == fetched {
+				l.Debug("Module revision is unchanged - skip fetching remote module", "revision", rev)
+				break
+			}
+		}
+		// The revision is forgotten until the module is fetched, so that a
+		// fetch that is interrupted is never mistaken for a complete one.
+		if err := c.fs.Remove(filepath.Join(dir, tfModuleRevision)); resource.Ignore(os.IsNotExist, err) != nil {
+			return nil, errors.Wrap(err, errWriteModuleRevision)
 		}
-		err := gc.Get()
+		files, err := c.getRemoteModule(ctx, cr.Spec.ForProvider.Module, moduleDir, dir, gitCreds.Key(), gitEnv)
 		if err != nil {
 			return nil, errors.Wrap(err, errRemoteModule)
 		}
+		// Modules copied from the module cache don't remove files that were
+		// removed from their source. Verified modules are pruned once copied.
+		if files != nil && !verifyModule {
+			if err := workdir.Prune(c.fs, dir, files); err != nil {
+				return nil, errors.Wrap(err, errPruneModule)
+			}
+		}
+		if rev == "" {
+			break
+		}
+		if err := c.fs.WriteFile(filepath.Join(dir, tfModuleRevision), []byte(fetched), 0600); err != nil {
+			return nil, errors.Wrap(err, errWriteModuleRevision)
+		}
 
 	case v1beta1.ModuleSourceInline:
 		fn := tfMain
 		if cr.Spec.ForProvider.InlineFormat == v1beta1.FileFormatJSON {
 			fn = tfMainJSON
 		}
-		if err := c.fs.WriteFile(filepath.Join(dir, fn), []byte(cr.Spec.ForProvider.Module), 0600); err != nil {
-			return nil, errors.Wrap(err, errWriteMain+fn)
+		var files []string
+		if cr.Spec.ForProvider.Module != "" || len(cr.Spec.ForProvider.InlineFiles) == 0 {
+			if err := c.fs.WriteFile(filepath.Join(dir, fn), []byte(cr.Spec.ForProvider.Module), 0600); err != nil {
+				return nil, errors.Wrap(err, errWriteMain+fn)
+			}
+			files = append(files, fn)
+		}
+		written, err := workdir.WriteFiles(c.fs, dir, cr.Spec.ForProvider.InlineFiles)
+		if err != nil {
+			return nil, errors.Wrap(err, errWriteInlineFiles)
+		}
+		// Files that were removed from the Workspace must not be applied.
+		if err := workdir.Prune(c.fs, dir, append(files, written...)); err != nil {
+			return nil, errors.Wrap(err, errPruneModule)
 		}
 
 	case v1beta1.ModuleSourceFlux:
-		url, err := c.getFluxArtefactURL(ctx, cr.Spec.ForProvider.Module)
+		a, err := c.getFluxArtifact(ctx, cr.Spec.ForProvider.Module)
 		if err != nil {
 			return nil, errors.Wrap(err, errFluxArtefactModule)
 		}
+		digest, rev = a.Digest, a.Revision
 		gc := getter.Client{
-			Src: url,
-			Dst: dir,
+			Src: a.URL,
+			Dst: moduleDir,
 			Pwd: dir,
 
 			Mode: getter.ClientModeDir,
@@ -279,6 +576,32 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		if err != nil {
 			return nil, errors.Wrap(err, errFluxArtefactModule)
 		}
+
+	case v1beta1.ModuleSourceOCI:
+		digest, err = c.pullOCIModule(ctx, l, cr.Spec.ForProvider.Module, dir, pc.Spec.OCIPullSecrets)
+		if err != nil {
+			return nil, err
+		}
+
+	case v1beta1.ModuleSourceConfigMap:
+		if err := c.writeModuleConfigMaps(ctx, cr, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if verifyModule {
+		if err := c.verifyModule(ctx, cr, moduleDir); err != nil {
+			cr.Status.SetConditions(v1beta1.ModuleVerificationFailed(err))
+			return nil, errors.Wrap(err, errVerifyModule)
+		}
+		cr.Status.SetConditions(v1beta1.ModuleVerified())
+		files, err := workdir.Copy(c.fs, moduleDir, dir)
+		if err != nil {
+			return nil, errors.Wrap(err, errCopyModule)
+		}
+		if err := workdir.Prune(c.fs, dir, files); err != nil {
+			return nil, errors.Wrap(err, errPruneModule)
+		}
 	}
 
 	if len(cr.Spec.ForProvider.Entrypoint) > 0 {
@@ -349,7 +672,29 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		envs[idx] = strings.Join([]string{env.Name, runtimeVal}, "=")
 	}
 
-	tf := c.terraform(dir, *pc.Spec.PluginCache, cr.Spec.ForProvider.EnableTerraformCLILogging, l, envs...)
+	// Registry tokens are passed before the Workspace's environment variables,
+	// so that the Workspace may override them.
+	tokens := make([]string, 0, len(pc.Spec.RegistryCredentials))
+	for _, rc := range pc.Spec.RegistryCredentials {
+		token, err := resource.CommonCredentialExtractor(ctx, xpv1.CredentialsSourceSecret, c.kube, xpv1.CommonCredentialSelectors{SecretRef: &rc.TokenSecretRef})
+		if err != nil {
+			return nil, errors.Wrap(err, errGetRegistryToken)
+		}
+		tokens = append(tokens, terraform.RegistryTokenEnv(rc.Hostname, strings.TrimSpace(string(token))))
+	}
+
+	usage, err := c.checkDiskUsage(cr, workDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	runner, err := c.runner(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	envs = append(append(gitEnv, tokens...), envs...)
+	tf := c.terraform(dir, *pc.Spec.PluginCache, cr.Spec.ForProvider.EnableTerraformCLILogging, l, runner, c.limiter(cr, pc.Spec.MaxConcurrentRuns), envs...)
 	if cr.Status.AtProvider.Checksum != "" {
 		checksum, err := tf.GenerateChecksum(ctx)
 		if err != nil {
@@ -357,7 +702,7 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		}
 		if cr.Status.AtProvider.Checksum == checksum {
 			l.Debug("Checksums match - skip running terraform init")
-			return &external{tf: tf, kube: c.kube, logger: c.logger}, errors.Wrap(tf.Workspace(ctx, meta.GetExternalName(cr)), errWorkspace)
+			return &external{tf: tf, kube: c.kube, logger: c.logger, fs: c.fs, workDirs: workDirs, metrics: c.metrics, moduleDigest: digest, moduleRevision: rev, diskUsage: usage, timeouts: timeouts}, errors.Wrap(tf.Workspace(ctx, meta.GetExternalName(cr)), errWorkspace)
 		}
 		l.Debug("Checksums don't match so run terraform init:", "old", cr.Status.AtProvider.Checksum, "new", checksum)
 	}
@@ -367,66 +712,310 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		o = append(o, terraform.WithInitArgs([]string{"-backend-config=" + filepath.Join(dir, tfBackendFile)}))
 	}
 	o = append(o, terraform.WithInitArgs(cr.Spec.ForProvider.InitArgs))
-	if err := tf.Init(ctx, o...); err != nil {
+	if err := withTimeout(ctx, "init", timeouts.Init, func(ctx context.Context) error { return tf.Init(ctx, o...) }); err != nil {
 		return nil, errors.Wrap(err, errInit)
 	}
-	return &external{tf: tf, kube: c.kube}, errors.Wrap(tf.Workspace(ctx, meta.GetExternalName(cr)), errWorkspace)
+	return &external{tf: tf, kube: c.kube, logger: c.logger, fs: c.fs, workDirs: workDirs, metrics: c.metrics, moduleDigest: digest, moduleRevision: rev, diskUsage: usage, timeouts: timeouts}, errors.Wrap(tf.Workspace(ctx, meta.GetExternalName(cr)), errWorkspace)
+}
+
+// operationTimeouts returns the supplied default timeouts, overridden by the
+// supplied Workspace timeouts.
+func operationTimeouts(defaults options.Timeouts, t *v1beta1.Timeouts) options.Timeouts {
+	if t == nil {
+		return defaults
+	}
+	if t.Init != nil {
+		defaults.Init = t.Init.Duration
+	}
+	if t.Plan != nil {
+		defaults.Plan = t.Plan.Duration
+	}
+	if t.Apply != nil {
+		defaults.Apply = t.Apply.Duration
+	}
+	if t.Destroy != nil {
+		defaults.Destroy = t.Destroy.Duration
+	}
+	return defaults
+}
+
+// withTimeout calls the supplied function, which runs the supplied Terraform
+// operation, with a context that is done after the supplied timeout. The
+// timeout is ignored if it's zero. Errors caused by the timeout say so.
+func withTimeout(ctx context.Context, op string, d time.Duration, fn func(ctx context.Context) error) error {
+	if d <= 0 {
+		return fn(ctx)
+	}
+	tctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	err := fn(tctx)
+	if err != nil && ctx.Err() == nil && errors.Is(tctx.Err(), context.DeadlineExceeded) {
+		return errors.Wrapf(err, errFmtTimeout, op, d)
+	}
+	return err
+}
+
+// limiter returns the Limiter that bounds how many Terraform processes run at
+// once for the Workspaces that share the supplied Workspace's ProviderConfig,
+// or nil if there is no limit.
+func (c *connector) limiter(cr *v1beta1.Workspace, limit *int) terraform.Limiter {
+	if limit == nil || c.limits == nil {
+		return nil
+	}
+	// Workspaces reference cluster scoped ProviderConfigs by name.
+	key := v1beta1.ProviderConfigGroupKind + "/" + cr.GetProviderConfigReference().Name
+	return c.limits.For(key, *limit)
+}
+
+// runner returns the Runner that plans, applies and destroys the supplied
+// Workspace, or nil if Terraform runs in the provider process.
+func (c *connector) runner(cr *v1beta1.Workspace) (terraform.Runner, error) {
+	e := cr.Spec.ForProvider.Execution
+	if e == nil || e.Mode != v1beta1.ExecutionModeJob {
+		return nil, nil
+	}
+	if c.jobs == nil {
+		return nil, errors.New(errJobsDisabled)
+	}
+	t := job.Template{}
+	if j := e.Job; j != nil {
+		t = job.Template{ServiceAccountName: j.ServiceAccountName, NodeSelector: j.NodeSelector, Tolerations: j.Tolerations}
+		if j.Resources != nil {
+			t.Resources = *j.Resources
+		}
+	}
+	r, err := c.jobs.Runner(t, map[string]string{job.LabelWorkspace: string(cr.GetUID())})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// checkDiskUsage returns the disk usage of the supplied working directories of
+// the supplied Workspace, as of when it was last measured. They're only
+// measured now if they weren't measured before. It returns an error if they
+// use more than the maximum disk usage, unless the Workspace is being deleted.
+func (c *connector) checkDiskUsage(cr *v1beta1.Workspace, dirs []string) (int64, error) {
+	usage, ok := c.diskUsage.Get(string(cr.GetUID()))
+	if !ok {
+		var err error
+		if usage, err = workdir.Usage(c.fs, dirs...); err != nil {
+			return 0, errors.Wrap(err, errDiskUsage)
+		}
+	}
+	c.metrics.RecordUsage(v1beta1.WorkspaceGroupVersionKind, cr.GetNamespace(), cr.GetName(), usage)
+	cr.Status.AtProvider.DiskUsageBytes = usage
+	if c.maxDiskUsage <= 0 {
+		return usage, nil
+	}
+	if usage > c.maxDiskUsage && !meta.WasDeleted(cr) {
+		err := errors.Errorf(errFmtDiskQuota, usage, c.maxDiskUsage)
+		cr.Status.SetConditions(v1beta1.DiskQuotaExceeded(err))
+		return usage, err
+	}
+	cr.Status.SetConditions(v1beta1.WithinDiskQuota())
+	return usage, nil
+}
+
+// resolveRevision resolves the revision of the supplied Remote module source,
+// returning an empty revision if it can't be resolved.
+func (c *connector) resolveRevision(ctx context.Context, l logging.Logger, src, pwd string, env []string) string {
+	if c.revisions == nil {
+		return ""
+	}
+	rev, err := c.revisions.Resolve(ctx, src, pwd, env...)
+	if err != nil {
+		l.Debug("Cannot resolve module revision - fetching remote module", "error", err)
+		return ""
+	}
+	return rev
 }
 
-func (c *connector) getFluxArtefactURL(ctx context.Context, fluxSourceName string) (string, error) {
-	regexResult := regexp.MustCompile(fmt.Sprintf(`(?i)^(%s|%s)::([^/]+)/(.+)$`, sourcev1.GitRepositoryKind, sourcev1beta2.OCIRepositoryKind))
-	matches := regexResult.FindStringSubmatch(fluxSourceName)
-	if len(matches) != 4 {
-		return "", fmt.Errorf("invalid module format for flux source. Expected 'FluxSourceKind::namespace/name', got '%s'", fluxSourceName)
+// getRemoteModule gets the module with the supplied source into the supplied
+// directory, using the module cache if there is one. Modules are cached per
+// distinct source and git credentials. Modules with local sources are never
+// cached. The paths of the module's files relative to the directory are
+// returned if it was copied from the cache.
+func (c *connector) getRemoteModule(ctx context.Context, src, dst, pwd string, creds []byte, env []string) ([]string, error) {
+	fetch := func(ctx context.Context, dir string) error {
+		gc := getter.Client{
+			Ctx: ctx,
+			Src: src,
+			Dst: dir,
+			Pwd: pwd,
+
+			Mode:    getter.ClientModeDir,
+			Getters: gitauth.Getters(env),
+		}
+		return gc.Get()
 	}
-	sourceKind := matches[1]
-	sourceNamespace := matches[2]
-	sourceName := matches[3]
 
-	objectKey := client.ObjectKey{
-		Namespace: sourceNamespace,
-		Name:      sourceName,
+	if c.moduleCache == nil {
+		return nil, fetch(ctx, dst)
+	}
+	u, err := getter.Detect(src, pwd, getter.Detectors)
+	if err != nil || strings.HasPrefix(u, "file://") {
+		return nil, fetch(ctx, dst)
 	}
+	_, files, err := c.moduleCache.Get(ctx, u, creds, dst, fetch)
+	return files, err
+}
 
-	var artefact *sourcev1.Artifact
-	switch strings.ToLower(sourceKind) {
-	case strings.ToLower(sourcev1.GitRepositoryKind):
-		var gitRepo sourcev1.GitRepository
-		err := c.kube.Get(ctx, objectKey, &gitRepo)
+// writeModuleConfigMaps writes the ConfigMaps the supplied Workspace reads its
+// module from to the supplied directory. Files that were removed from the
+// ConfigMaps are removed from the directory.
+func (c *connector) writeModuleConfigMaps(ctx context.Context, cr *v1beta1.Workspace, dir string) error {
+	var files []string
+	for _, ref := range cr.Spec.ForProvider.ModuleConfigMaps {
+		cm := &corev1.ConfigMap{}
+		if err := c.kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, cm); err != nil {
+			return errors.Wrap(err, errGetModuleConfigMap)
+		}
+		p, err := workdir.SafeJoin(dir, ref.Path)
 		if err != nil {
-			return "", err
+			return errors.Wrap(err, errWriteModuleConfigMap)
 		}
-		artefact = gitRepo.GetArtifact()
-	case strings.ToLower(sourcev1beta2.OCIRepositoryKind):
-		var ociRepo sourcev1beta2.OCIRepository
-		err := c.kube.Get(ctx, objectKey, &ociRepo)
+		written, err := workdir.WriteConfigMap(c.fs, p, cm)
 		if err != nil {
-			return "", err
+			return errors.Wrap(err, errWriteModuleConfigMap)
+		}
+		for _, f := range written {
+			files = append(files, filepath.Join(ref.Path, f))
 		}
-		artefact = ociRepo.GetArtifact()
 	}
+	return errors.Wrap(workdir.Prune(c.fs, dir, files), errPruneModule)
+}
 
-	if artefact == nil {
-		return "", fmt.Errorf("artefact for '%s/%s' %s is not ready", sourceNamespace, sourceName, sourceKind)
+// pullOCIModule pulls the OCI module with the supplied source into the
+// supplied directory, authenticating using the supplied pull secrets, and
+// returns its digest. Files of an earlier pull that are no longer part of the
+// module are removed. The module isn't pulled again if its digest is unchanged.
+func (c *connector) pullOCIModule(ctx context.Context, l logging.Logger, src, dir string, secrets []xpv1.SecretReference) (string, error) {
+	o := make([]oci.PullOption, 0, len(secrets)+1)
+	for _, ref := range secrets {
+		s := &corev1.Secret{}
+		if err := c.kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+			return "", errors.Wrap(err, errGetPullSecret)
+		}
+		o = append(o, oci.WithDockerConfigJSON(s.Data[corev1.DockerConfigJsonKey]))
 	}
 
-	return artefact.URL, nil
+	rf := filepath.Join(dir, tfModuleRevision)
+	if b, err := c.fs.ReadFile(rf); err == nil {
+		if d, ok := strings.CutPrefix(string(b), src+"@"); ok {
+			o = append(o, oci.WithPulledDigest(d))
+		}
+	}
+	// The digest is forgotten until the module is pulled, so that a pull that
+	// is interrupted is never mistaken for a complete one.
+	if err := c.fs.Remove(rf); resource.Ignore(os.IsNotExist, err) != nil {
+		return "", errors.Wrap(err, errWriteModuleRevision)
+	}
+	digest, files, err := oci.Pull(ctx, c.fs, src, dir, o...)
+	if err != nil {
+		return "", errors.Wrap(err, errOCIModule)
+	}
+	if files == nil {
+		l.Debug("Module digest is unchanged - skip pulling OCI module", "digest", digest)
+	} else if err := workdir.Prune(c.fs, dir, files); err != nil {
+		return "", errors.Wrap(err, errPruneModule)
+	}
+	return digest, errors.Wrap(c.fs.WriteFile(rf, []byte(src+"@"+digest), 0600), errWriteModuleRevision)
+}
+
+// verifyModule verifies the module in the supplied directory against the
+// Workspace's digest and signature, failing closed if neither is specified.
+func (c *connector) verifyModule(ctx context.Context, cr *v1beta1.Workspace, dir string) error {
+	v := cr.Spec.ForProvider.Verification
+	if v.Digest == "" && v.Signature == nil {
+		return errors.New(errNoVerification)
+	}
+
+	m, err := verify.Manifest(c.fs, dir)
+	if err != nil {
+		return err
+	}
+
+	if v.Digest != "" {
+		if err := verify.VerifyDigest(m, v.Digest); err != nil {
+			return err
+		}
+	}
+
+	if v.Signature == nil {
+		return nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(v.Signature.Signature)
+	if err != nil {
+		return errors.Wrap(err, errDecodeSignature)
+	}
+	s := &corev1.Secret{}
+	nn := types.NamespacedName{Namespace: v.Signature.PublicKeysSecretRef.Namespace, Name: v.Signature.PublicKeysSecretRef.Name}
+	if err := c.kube.Get(ctx, nn, s); err != nil {
+		return errors.Wrap(err, errGetPublicKeys)
+	}
+	keys := make([][]byte, 0, len(s.Data))
+	for _, k := range s.Data {
+		keys = append(keys, k)
+	}
+	return verify.VerifySignature(m, sig, keys...)
+}
+
+// getFluxArtifact returns the latest artifact of the Flux source referenced
+// by the supplied module.
+func (c *connector) getFluxArtifact(ctx context.Context, module string) (*sourcev1.Artifact, error) {
+	src, err := flux.ParseSource(module)
+	if err != nil {
+		return nil, err
+	}
+	return flux.GetArtifact(ctx, c.kube, src)
 }
 
 type external struct {
 	tf     tfclient
 	kube   client.Client
 	logger logging.Logger
+	fs     afero.Afero
+
+	// workDirs are the working directories of the Workspace. They're
+	// removed once it has been deleted.
+	workDirs []string
+
+	// metrics records the disk usage of the working directories, if it is
+	// not nil.
+	metrics *workdir.Metrics
+
+	// diskUsage is the disk usage of the working directories.
+	diskUsage int64
+
+	// moduleDigest is the digest of the module fetched by Connect, if any.
+	moduleDigest string
+
+	// moduleRevision is the revision of the module fetched by Connect, if
+	// any.
+	moduleRevision string
+
+	// timeouts of the Workspace's Terraform operations.
+	timeouts options.Timeouts
 }
 
 func (c *external) checkDiff(ctx context.Context, cr *v1beta1.Workspace) (bool, error) {
+	if cr.Spec.ForProvider.PauseOperations {
+		// Plans are skipped entirely, so no cloud APIs are called.
+		return false, nil
+	}
+
 	o, err := c.options(ctx, cr.Spec.ForProvider)
 	if err != nil {
 		return false, errors.Wrap(err, errOptions)
 	}
 
 	o = append(o, terraform.WithArgs(cr.Spec.ForProvider.PlanArgs))
-	differs, err := c.tf.Diff(ctx, o...)
+	var differs bool
+	err = withTimeout(ctx, "plan", c.timeouts.Plan, func(ctx context.Context) error {
+		differs, err = c.tf.Diff(ctx, o...)
+		return err
+	})
 	if err != nil {
 		if !meta.WasDeleted(cr) {
 			return false, errors.Wrap(err, errDiff)
@@ -463,7 +1052,16 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	if err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, errOutputs)
 	}
-	cr.Status.AtProvider = generateWorkspaceObservation(op)
+	cr.Status.AtProvider = generateWorkspaceObservation(cr, op)
+	cr.Status.AtProvider.ModuleDigest = c.moduleDigest
+	cr.Status.AtProvider.ModuleRevision = c.moduleRevision
+	cr.Status.AtProvider.DiskUsageBytes = c.diskUsage
+	if !meta.WasDeleted(cr) {
+		checkOutputKeys(cr, op)
+		if err := c.publishOutputs(ctx, cr, op); err != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, errPublishOutputs)
+		}
+	}
 
 	checksum, err := c.tf.GenerateChecksum(ctx)
 	if err != nil {
@@ -471,21 +1069,56 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	}
 	cr.Status.AtProvider.Checksum = checksum
 
-	if !differs {
+	switch {
+	case cr.Spec.ForProvider.PauseOperations:
+		// The Workspace wasn't planned, so we don't know whether it's ready.
+		cr.Status.SetConditions(v1beta1.OperationsPaused())
+	case !differs:
 		// TODO(negz): Allow Workspaces to optionally derive their readiness from an
 		// output - similar to the logic XRs use to derive readiness from a field of
 		// a composed resource.
 		cr.Status.SetConditions(xpv1.Available())
 	}
 
+	exists := resourceExists(cr, r, op)
+	if meta.WasDeleted(cr) && !exists {
+		// The Workspace will be deleted when we return. Remove its working
+		// directories now, rather than waiting for them to be garbage
+		// collected.
+		c.removeWorkDirs(cr)
+	}
+
 	return managed.ExternalObservation{
-		ResourceExists:          len(r)+len(op) > 0,
+		ResourceExists:          exists,
 		ResourceUpToDate:        !differs,
 		ResourceLateInitialized: false,
-		ConnectionDetails:       op2cd(op),
+		ConnectionDetails:       op2cd(op, cr.Spec.ForProvider.ConnectionDetailsMapping),
 	}, nil
 }
 
+// resourceExists returns true if the supplied Workspace's state contains any
+// resources or outputs. A Workspace whose operations are paused must not be
+// created, i.e. applied, so it's always reported to exist unless it's being
+// deleted.
+func resourceExists(cr *v1beta1.Workspace, r []string, op []terraform.Output) bool {
+	if cr.Spec.ForProvider.PauseOperations && !meta.WasDeleted(cr) {
+		return true
+	}
+	return len(r)+len(op) > 0
+}
+
+// removeWorkDirs removes the working directories of the supplied Workspace.
+// Failures are only logged; the garbage collector removes directories that
+// remain.
+func (c *external) removeWorkDirs(cr *v1beta1.Workspace) {
+	for _, dir := range c.workDirs {
+		if err := c.fs.RemoveAll(dir); err != nil {
+			c.logger.Debug("Cannot remove working directory", "dir", dir, "error", err)
+		}
+	}
+	c.metrics.DeleteUsage(v1beta1.WorkspaceGroupVersionKind, cr.GetNamespace(), cr.GetName())
+}
+
 func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
 	// Terraform does not have distinct 'create' and 'update' operations.
 	u, err := c.Update(ctx, mg)
@@ -497,6 +1130,9 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 	if !ok {
 		return managed.ExternalUpdate{}, errors.New(errNotWorkspace)
 	}
+	if cr.Spec.ForProvider.PauseOperations {
+		return managed.ExternalUpdate{}, errors.Wrap(errors.New(errOperationsPaused), errApply)
+	}
 
 	o, err := c.options(ctx, cr.Spec.ForProvider)
 	if err != nil {
@@ -504,7 +1140,7 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 	}
 
 	o = append(o, terraform.WithArgs(cr.Spec.ForProvider.ApplyArgs))
-	if err := c.tf.Apply(ctx, o...); err != nil {
+	if err := withTimeout(ctx, "apply", c.timeouts.Apply, func(ctx context.Context) error { return c.tf.Apply(ctx, o...) }); err != nil {
 		return managed.ExternalUpdate{}, errors.Wrap(err, errApply)
 	}
 
@@ -512,7 +1148,14 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 	if err != nil {
 		return managed.ExternalUpdate{}, errors.Wrap(err, errOutputs)
 	}
-	cr.Status.AtProvider = generateWorkspaceObservation(op)
+	cr.Status.AtProvider = generateWorkspaceObservation(cr, op)
+	cr.Status.AtProvider.ModuleDigest = c.moduleDigest
+	cr.Status.AtProvider.ModuleRevision = c.moduleRevision
+	cr.Status.AtProvider.DiskUsageBytes = c.diskUsage
+	checkOutputKeys(cr, op)
+	if err := c.publishOutputs(ctx, cr, op); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errPublishOutputs)
+	}
 	// TODO(negz): Allow Workspaces to optionally derive their readiness from an
 	// output - similar to the logic XRs use to derive readiness from a field of
 	// a composed resource.
@@ -520,7 +1163,7 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 	// on the first pass and it will get reset to Available() by Observe() on the next pass if there are no differences.
 	// Leave this call for the Update() case.
 	cr.Status.SetConditions(xpv1.Available())
-	return managed.ExternalUpdate{ConnectionDetails: op2cd(op)}, nil
+	return managed.ExternalUpdate{ConnectionDetails: op2cd(op, cr.Spec.ForProvider.ConnectionDetailsMapping)}, nil
 }
 
 func (c *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
@@ -528,6 +1171,9 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) (managed.Ext
 	if !ok {
 		return managed.ExternalDelete{}, errors.New(errNotWorkspace)
 	}
+	if cr.Spec.ForProvider.PauseOperations {
+		return managed.ExternalDelete{}, errors.Wrap(errors.New(errOperationsPaused), errDestroy)
+	}
 
 	o, err := c.options(ctx, cr.Spec.ForProvider)
 	if err != nil {
@@ -535,7 +1181,8 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) (managed.Ext
 	}
 
 	o = append(o, terraform.WithArgs(cr.Spec.ForProvider.DestroyArgs))
-	return managed.ExternalDelete{}, errors.Wrap(c.tf.Destroy(ctx, o...), errDestroy)
+	err = withTimeout(ctx, "destroy", c.timeouts.Destroy, func(ctx context.Context) error { return c.tf.Destroy(ctx, o...) })
+	return managed.ExternalDelete{}, errors.Wrap(err, errDestroy)
 }
 
 func (c *external) Disconnect(ctx context.Context) error {
@@ -588,32 +1235,203 @@ func (c *external) options(ctx context.Context, p v1beta1.WorkspaceParameters) (
 	return o, nil
 }
 
-func op2cd(o []terraform.Output) managed.ConnectionDetails {
-	cd := managed.ConnectionDetails{}
+// publishOutputs writes the selected non-sensitive outputs to the ConfigMap
+// configured by the Workspace, if any. The ConfigMap is controlled by the
+// Workspace and is garbage collected when the Workspace is deleted. The
+// ConfigMap outputs were previously published to is deleted when the
+// Workspace stops publishing outputs to it.
+func (c *external) publishOutputs(ctx context.Context, cr *v1beta1.Workspace, op []terraform.Output) error {
+	if err := c.deleteStaleOutputs(ctx, cr); err != nil {
+		return err
+	}
+	ocm := cr.Spec.ForProvider.OutputsConfigMap
+	if ocm == nil {
+		cr.Status.AtProvider.OutputsConfigMap = nil
+		return nil
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ocm.Name,
+			Namespace:       ocm.Namespace,
+			OwnerReferences: []metav1.OwnerReference{meta.AsController(meta.TypedReferenceTo(cr, v1beta1.WorkspaceGroupVersionKind))},
+		},
+		Data: op2cm(op, ocm.Outputs),
+	}
+	if err := resource.NewAPIUpdatingApplicator(c.kube).Apply(ctx, cm, resource.MustBeControllableBy(cr.GetUID())); err != nil {
+		return err
+	}
+	cr.Status.AtProvider.OutputsConfigMap = &v1beta1.PublishedConfigMap{
+		Name:      ocm.Name,
+		Namespace: ocm.Namespace,
+	}
+	return nil
+}
+
+// deleteStaleOutputs deletes the ConfigMap to which outputs were last
+// published if the Workspace no longer publishes outputs to it. ConfigMaps
+// that are not controlled by the Workspace are left alone.
+func (c *external) deleteStaleOutputs(ctx context.Context, cr *v1beta1.Workspace) error {
+	prev := cr.Status.AtProvider.OutputsConfigMap
+	if prev == nil {
+		return nil
+	}
+	if ocm := cr.Spec.ForProvider.OutputsConfigMap; ocm != nil && ocm.Name == prev.Name && ocm.Namespace == prev.Namespace {
+		return nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.kube.Get(ctx, types.NamespacedName{Namespace: prev.Namespace, Name: prev.Name}, cm); err != nil {
+		return errors.Wrap(resource.IgnoreNotFound(err), errGetStaleOutputs)
+	}
+	if metav1.IsControlledBy(cm, cr) {
+		if err := c.kube.Delete(ctx, cm); resource.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, errDeleteStaleOutputs)
+		}
+	}
+	cr.Status.AtProvider.OutputsConfigMap = nil
+	return nil
+}
+
+// op2cm converts the non-sensitive outputs selected by the supplied mappings
+// to ConfigMap data. All non-sensitive outputs are converted when no mappings
+// are supplied.
+func op2cm(o []terraform.Output, m []v1beta1.OutputMapping) map[string]string {
+	ns := make([]terraform.Output, 0, len(o))
 	for _, op := range o {
-		if op.Type == terraform.OutputTypeString {
-			cd[op.Name] = []byte(op.StringValue())
+		if !op.Sensitive {
+			ns = append(ns, op)
+		}
+	}
+	data := map[string]string{}
+	values, _ := mapOutputs(ns, m)
+	for k, v := range values {
+		data[k] = string(v)
+	}
+	return data
+}
+
+// op2cd converts the outputs selected by the supplied mappings to connection
+// details. All outputs are converted when no mappings are supplied.
+func op2cd(o []terraform.Output, m []v1beta1.OutputMapping) managed.ConnectionDetails {
+	values, _ := mapOutputs(o, m)
+	return managed.ConnectionDetails(values)
+}
+
+// checkOutputKeys sets a condition reporting whether the keys under which the
+// supplied Workspace's outputs are published as connection details and to
+// its outputs ConfigMap are valid. Outputs with invalid keys aren't published.
+// The condition is only set once a key was invalid.
+func checkOutputKeys(cr *v1beta1.Workspace, op []terraform.Output) {
+	_, invalid := mapOutputs(op, cr.Spec.ForProvider.ConnectionDetailsMapping)
+	if ocm := cr.Spec.ForProvider.OutputsConfigMap; ocm != nil {
+		_, cmInvalid := mapOutputs(op, ocm.Outputs)
+		invalid = append(invalid, cmInvalid...)
+	}
+	if len(invalid) > 0 {
+		slices.Sort(invalid)
+		invalid = slices.Compact(invalid)
+		cr.Status.SetConditions(v1beta1.OutputKeysInvalid(errors.Errorf(errFmtInvalidOutputKeys, strings.Join(invalid, ", "))))
+		return
+	}
+	if cr.Status.GetCondition(v1beta1.TypeOutputKeys).Status != corev1.ConditionUnknown {
+		cr.Status.SetConditions(v1beta1.OutputKeysValid())
+	}
+}
+
+// mapOutputs returns the values of the outputs selected by the supplied
+// mappings, keyed as the mappings specify. String values are returned as is,
+// while all other values are JSON encoded. All outputs are returned under
+// their own names when no mappings are supplied. Values whose keys aren't
+// valid ConfigMap or Secret keys aren't returned; their keys are returned
+// instead.
+func mapOutputs(o []terraform.Output, m []v1beta1.OutputMapping) (map[string][]byte, []string) {
+	if len(m) == 0 {
+		m = make([]v1beta1.OutputMapping, 0, len(o))
+		for _, op := range o {
+			m = append(m, v1beta1.OutputMapping{Name: op.Name})
+		}
+	}
+	outputs := make(map[string]terraform.Output, len(o))
+	for _, op := range o {
+		outputs[op.Name] = op
+	}
+	values := make(map[string][]byte, len(m))
+	var invalid []string
+	set := func(k string, v []byte) {
+		if len(validation.IsConfigMapKey(k)) > 0 {
+			invalid = append(invalid, k)
+			return
+		}
+		values[k] = v
+	}
+	for _, om := range m {
+		op, ok := outputs[om.Name]
+		if !ok {
 			continue
 		}
-		if j, err := op.JSONValue(); err == nil {
-			cd[op.Name] = j
+		key := om.Key
+		if key == "" {
+			key = om.Name
+		}
+		if !om.Flatten {
+			if op.Type == terraform.OutputTypeString {
+				set(key, []byte(op.StringValue()))
+				continue
+			}
+			if j, err := op.JSONValue(); err == nil {
+				set(key, j)
+			}
+			continue
+		}
+		for path, v := range op.Flatten() {
+			k := key
+			if path != "" {
+				k = key + "." + path
+			}
+			if s, ok := v.(string); ok {
+				set(k, []byte(s))
+				continue
+			}
+			if j, err := json.Marshal(v); err == nil {
+				set(k, j)
+			}
 		}
 	}
-	return cd
+	return values, invalid
 }
 
 // generateWorkspaceObservation is used to produce v1beta1.WorkspaceObservation from
 // workspace_type.Workspace.
-func generateWorkspaceObservation(op []terraform.Output) v1beta1.WorkspaceObservation {
+func generateWorkspaceObservation(cr *v1beta1.Workspace, op []terraform.Output) v1beta1.WorkspaceObservation {
 	wo := v1beta1.WorkspaceObservation{
-		Outputs: make(map[string]extensionsV1.JSON, len(op)),
+		Outputs:          make(map[string]extensionsV1.JSON, len(op)),
+		OutputsConfigMap: cr.Status.AtProvider.OutputsConfigMap,
 	}
 	for _, o := range op {
 		if !o.Sensitive {
 			if j, err := o.JSONValue(); err == nil {
 				wo.Outputs[o.Name] = extensionsV1.JSON{Raw: j}
 			}
+			continue
+		}
+		if !cr.Spec.ForProvider.EnableSensitiveOutputHashes {
+			continue
+		}
+		if wo.SensitiveOutputs == nil {
+			wo.SensitiveOutputs = map[string]string{}
+		}
+		if j, err := o.JSONValue(); err == nil {
+			wo.SensitiveOutputs[o.Name] = hashOutput(cr.GetUID(), j)
 		}
 	}
 	return wo
 }
+
+// hashOutput returns a hex encoded HMAC-SHA256 of the supplied output value,
+// keyed by the supplied UID. Keying the hash by the UID of the Workspace
+// prevents values from being compared across Workspaces, or looked up in
+// precomputed tables.
+func hashOutput(uid types.UID, value []byte) string {
+	h := hmac.New(sha256.New, []byte(uid))
+	h.Write(value)
+	return hex.EncodeToString(h.Sum(nil))
+}
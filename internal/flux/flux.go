@@ -0,0 +1,155 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package flux reads the artifacts of Flux sources.
+package flux
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Error strings.
+const (
+	errFmtInvalidSource = "invalid module format for flux source. Expected 'FluxSourceKind::namespace/name', got '%s'"
+	errFmtUnknownKind   = "unknown flux source kind %q"
+	errFmtNotReady      = "artefact for '%s/%s' %s is not ready"
+	errConvertArtifact  = "cannot convert flux artifact"
+)
+
+// ExternalArtifactKind is the kind of a Flux ExternalArtifact. Unlike the
+// other kinds of source it is not part of the Flux source API this provider
+// is built against, so it is read as an unstructured object.
+const ExternalArtifactKind = "ExternalArtifact"
+
+// ExternalArtifactGroupVersionKind is the GroupVersionKind of a Flux
+// ExternalArtifact.
+var ExternalArtifactGroupVersionKind = sourcev1.GroupVersion.WithKind(ExternalArtifactKind)
+
+// Kinds of Flux source that may be referenced by a Workspace.
+var Kinds = []string{
+	sourcev1.GitRepositoryKind,
+	sourcev1beta2.OCIRepositoryKind,
+	sourcev1beta2.BucketKind,
+	ExternalArtifactKind,
+}
+
+var source = regexp.MustCompile(fmt.Sprintf(`(?i)^(%s)::([^/]+)/(.+)$`, strings.Join(Kinds, "|")))
+
+// A Source identifies a Flux source.
+type Source struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// ParseSource parses a Flux source in the Kind::namespace/name format. Kinds
+// are matched case insensitively.
+func ParseSource(s string) (Source, error) {
+	m := source.FindStringSubmatch(s)
+	if len(m) != 4 {
+		return Source{}, errors.Errorf(errFmtInvalidSource, s)
+	}
+	for _, k := range Kinds {
+		if strings.EqualFold(k, m[1]) {
+			return Source{Kind: k, Namespace: m[2], Name: m[3]}, nil
+		}
+	}
+	return Source{}, errors.Errorf(errFmtUnknownKind, m[1])
+}
+
+// New returns a new, empty object of the supplied kind of Flux source.
+func New(kind string) (client.Object, error) {
+	switch kind {
+	case sourcev1.GitRepositoryKind:
+		return &sourcev1.GitRepository{}, nil
+	case sourcev1beta2.OCIRepositoryKind:
+		return &sourcev1beta2.OCIRepository{}, nil
+	case sourcev1beta2.BucketKind:
+		return &sourcev1beta2.Bucket{}, nil
+	case ExternalArtifactKind:
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(ExternalArtifactGroupVersionKind)
+		return u, nil
+	}
+	return nil, errors.Errorf(errFmtUnknownKind, kind)
+}
+
+// GroupVersionKind returns the GroupVersionKind of the supplied kind of Flux
+// source.
+func GroupVersionKind(kind string) schema.GroupVersionKind {
+	switch kind {
+	case sourcev1.GitRepositoryKind, ExternalArtifactKind:
+		return sourcev1.GroupVersion.WithKind(kind)
+	}
+	return sourcev1beta2.GroupVersion.WithKind(kind)
+}
+
+// GetArtifact returns the latest artifact of the supplied Flux source. It
+// returns an error if the source does not yet have an artifact.
+func GetArtifact(ctx context.Context, kube client.Reader, s Source) (*sourcev1.Artifact, error) {
+	o, err := New(s.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := kube.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: s.Name}, o); err != nil {
+		return nil, err
+	}
+
+	var a *sourcev1.Artifact
+	switch src := o.(type) {
+	case *sourcev1.GitRepository:
+		a = src.GetArtifact()
+	case *sourcev1beta2.OCIRepository:
+		a = src.GetArtifact()
+	case *sourcev1beta2.Bucket:
+		a = src.GetArtifact()
+	case *unstructured.Unstructured:
+		if a, err = unstructuredArtifact(src); err != nil {
+			return nil, err
+		}
+	}
+
+	if a == nil {
+		return nil, errors.Errorf(errFmtNotReady, s.Namespace, s.Name, s.Kind)
+	}
+	return a, nil
+}
+
+func unstructuredArtifact(u *unstructured.Unstructured) (*sourcev1.Artifact, error) {
+	m, ok, err := unstructured.NestedMap(u.Object, "status", "artifact")
+	if err != nil {
+		return nil, errors.Wrap(err, errConvertArtifact)
+	}
+	if !ok {
+		return nil, nil
+	}
+	a := &sourcev1.Artifact{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, a); err != nil {
+		return nil, errors.Wrap(err, errConvertArtifact)
+	}
+	return a, nil
+}
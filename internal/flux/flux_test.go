@@ -0,0 +1,144 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flux
+
+import (
+	"context"
+	"testing"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+)
+
+func TestParseSource(t *testing.T) {
+	type want struct {
+		s   Source
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		s      string
+		want   want
+	}{
+		"GitRepository": {
+			reason: "A GitRepository source should be parsed.",
+			s:      "GitRepository::flux-system/repo",
+			want:   want{s: Source{Kind: sourcev1.GitRepositoryKind, Namespace: "flux-system", Name: "repo"}},
+		},
+		"CaseInsensitive": {
+			reason: "Kinds should be matched case insensitively.",
+			s:      "bucket::flux-system/bucket",
+			want:   want{s: Source{Kind: sourcev1beta2.BucketKind, Namespace: "flux-system", Name: "bucket"}},
+		},
+		"ExternalArtifact": {
+			reason: "An ExternalArtifact source should be parsed.",
+			s:      "ExternalArtifact::flux-system/artifact",
+			want:   want{s: Source{Kind: ExternalArtifactKind, Namespace: "flux-system", Name: "artifact"}},
+		},
+		"Invalid": {
+			reason: "A malformed source should return an error.",
+			s:      "HelmChart::flux-system/chart",
+			want:   want{err: errors.Errorf(errFmtInvalidSource, "HelmChart::flux-system/chart")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s, err := ParseSource(tc.s)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nParseSource(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.s, s); diff != "" {
+				t.Errorf("\n%s\nParseSource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetArtifact(t *testing.T) {
+	errBoom := errors.New("boom")
+	artifact := &sourcev1.Artifact{
+		URL:      "http://source-controller/bucket.tar.gz",
+		Revision: "sha256:cafe",
+		Digest:   "sha256:beef",
+	}
+
+	type want struct {
+		a   *sourcev1.Artifact
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		kube   client.Reader
+		s      Source
+		want   want
+	}{
+		"GetError": {
+			reason: "Errors getting the source should be returned.",
+			kube:   &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			s:      Source{Kind: sourcev1beta2.BucketKind, Namespace: "flux-system", Name: "bucket"},
+			want:   want{err: errBoom},
+		},
+		"Bucket": {
+			reason: "The artifact of a Bucket should be returned.",
+			kube: &test.MockClient{MockGet: test.NewMockGetFn(nil, func(o client.Object) error {
+				o.(*sourcev1beta2.Bucket).Status.Artifact = artifact
+				return nil
+			})},
+			s:    Source{Kind: sourcev1beta2.BucketKind, Namespace: "flux-system", Name: "bucket"},
+			want: want{a: artifact},
+		},
+		"ExternalArtifact": {
+			reason: "The artifact of an ExternalArtifact should be returned.",
+			kube: &test.MockClient{MockGet: test.NewMockGetFn(nil, func(o client.Object) error {
+				return unstructured.SetNestedMap(o.(*unstructured.Unstructured).Object, map[string]any{
+					"url":      artifact.URL,
+					"revision": artifact.Revision,
+					"digest":   artifact.Digest,
+				}, "status", "artifact")
+			})},
+			s:    Source{Kind: ExternalArtifactKind, Namespace: "flux-system", Name: "artifact"},
+			want: want{a: artifact},
+		},
+		"NotReady": {
+			reason: "An error should be returned if the source has no artifact.",
+			kube:   &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+			s:      Source{Kind: ExternalArtifactKind, Namespace: "flux-system", Name: "artifact"},
+			want:   want{err: errors.Errorf(errFmtNotReady, "flux-system", "artifact", ExternalArtifactKind)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a, err := GetArtifact(context.Background(), tc.kube, tc.s)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nGetArtifact(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.a, a); diff != "" {
+				t.Errorf("\n%s\nGetArtifact(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitauth
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	getter "github.com/hashicorp/go-getter"
+	"github.com/pkg/errors"
+)
+
+// Error strings.
+const (
+	errGetFile    = "cannot fetch a single file from a git repository"
+	errStat       = "cannot stat destination directory"
+	errReadDir    = "cannot read destination directory"
+	errRemoveGit  = "cannot remove .git directory of destination directory"
+	errFmtGit     = "git %s failed: %s"
+	errFmtShallow = "%s (note that setting 'depth' requires 'ref' to be a branch or tag name)"
+)
+
+// defaultBranch is assumed to be the default branch of repositories whose
+// default branch cannot be determined, like go-getter does.
+const defaultBranch = "master"
+
+var (
+	// commitID matches refs that are likely to be commits rather than
+	// branches or tags.
+	commitID = regexp.MustCompile("^[0-9a-fA-F]{7,40}$")
+
+	// symref matches the default branch in the output of git ls-remote
+	// --symref.
+	symref = regexp.MustCompile(`(?m)^ref: refs/heads/(\S+)\s+HEAD$`)
+)
+
+// Getters returns go-getter's getters, with a git getter that runs git with
+// the supplied environment variables.
+func Getters(env []string) map[string]getter.Getter {
+	g := make(map[string]getter.Getter, len(getter.Getters))
+	for k, v := range getter.Getters {
+		g[k] = v
+	}
+	g["git"] = &Getter{Env: env}
+	return g
+}
+
+// A Getter fetches git repositories like go-getter's git getter, but runs git
+// with additional environment variables. go-getter runs git with the
+// environment of its process, which is shared by all concurrent reconciles.
+// Repositories are fetched by go-getter's git getter if there are no
+// additional environment variables, or if the source specifies an sshkey.
+type Getter struct {
+	getter.GitGetter
+
+	// Env is appended to the environment of the provider when running git.
+	Env []string
+
+	client *getter.Client
+}
+
+// SetClient sets the client that is using the Getter.
+func (g *Getter) SetClient(c *getter.Client) {
+	g.client = c
+	g.GitGetter.SetClient(c)
+}
+
+// GetFile is not supported.
+func (g *Getter) GetFile(_ string, _ *url.URL) error {
+	return errors.New(errGetFile)
+}
+
+// Get fetches the supplied git repository into dst, updating it if dst
+// already exists.
+func (g *Getter) Get(dst string, u *url.URL) error {
+	q := u.Query()
+	if len(g.Env) == 0 || q.Has("sshkey") {
+		return g.GitGetter.Get(dst, u)
+	}
+
+	ctx := g.Context()
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+
+	ref := q.Get("ref")
+	depth, _ := strconv.Atoi(q.Get("depth"))
+	q.Del("ref")
+	q.Del("depth")
+	src := *u
+	src.RawQuery = q.Encode()
+
+	_, err := os.Stat(dst)
+	switch {
+	case err == nil:
+		err = g.update(ctx, dst, src.String(), ref, depth)
+	case os.IsNotExist(err):
+		err = g.clone(ctx, dst, src.String(), ref, depth)
+	default:
+		err = errors.Wrap(err, errStat)
+	}
+	if err != nil {
+		return err
+	}
+
+	args := []string{"submodule", "update", "--init", "--recursive"}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
+	}
+	if g.client != nil {
+		g.client.DisableSymlinks = true
+	}
+	return g.git(ctx, dst, args...)
+}
+
+// clone the supplied repository into dst, and check out the supplied ref.
+func (g *Getter) clone(ctx context.Context, dst, src, ref string, depth int) error {
+	args := []string{"clone"}
+	if depth > 0 {
+		branch := ref
+		if branch == "" {
+			branch = g.defaultBranch(ctx, src)
+		}
+		args = append(args, "--depth", strconv.Itoa(depth), "--branch", branch)
+	}
+	if err := g.git(ctx, "", append(args, "--", src, dst)...); err != nil {
+		if depth > 0 && commitID.MatchString(ref) {
+			return errors.Errorf(errFmtShallow, err)
+		}
+		return err
+	}
+	if depth > 0 || ref == "" {
+		return nil
+	}
+	if err := g.git(ctx, dst, "checkout", ref); err != nil {
+		_ = os.RemoveAll(dst)
+		return err
+	}
+	return nil
+}
+
+// update the repository in dst to the supplied ref of the supplied
+// repository.
+func (g *Getter) update(ctx context.Context, dst, src, ref string, depth int) error {
+	files, err := os.ReadDir(dst)
+	if err != nil {
+		return errors.Wrap(err, errReadDir)
+	}
+	for _, f := range files {
+		if f.IsDir() && strings.EqualFold(f.Name(), ".git") {
+			if err := os.RemoveAll(filepath.Join(dst, f.Name())); err != nil {
+				return errors.Wrap(err, errRemoveGit)
+			}
+		}
+	}
+	if ref == "" {
+		ref = g.defaultBranch(ctx, src)
+	}
+	pull := []string{"pull", "origin", "--ff-only", "--", ref}
+	if depth > 0 {
+		pull = []string{"pull", "origin", "--depth", strconv.Itoa(depth), "--ff-only", "--", ref}
+	}
+	for _, args := range [][]string{
+		{"init"},
+		{"remote", "add", "origin", "--", src},
+		{"fetch", "--tags"},
+		{"fetch", "origin", "--", ref},
+		{"reset", "--hard", "FETCH_HEAD"},
+		{"checkout", ref},
+		pull,
+	} {
+		if err := g.git(ctx, dst, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultBranch returns the default branch of the supplied repository, or
+// master if it cannot be determined.
+func (g *Getter) defaultBranch(ctx context.Context, src string) string {
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--symref", "--", src, "HEAD")
+	cmd.Env = append(os.Environ(), g.Env...)
+	out, err := cmd.Output()
+	m := symref.FindSubmatch(out)
+	if err != nil || m == nil {
+		return defaultBranch
+	}
+	return string(m[1])
+}
+
+// git runs git with the supplied arguments in the supplied directory.
+func (g *Getter) git(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), g.Env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, errFmtGit, args[0], strings.TrimSpace(string(out)))
+	}
+	return nil
+}
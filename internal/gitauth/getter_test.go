@@ -0,0 +1,176 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitauth
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	getter "github.com/hashicorp/go-getter"
+)
+
+// repo creates a git repository with a main.tf file that has content v1 at
+// tag v1, and content v2 on branch main. It returns the repository's path and
+// the commit of tag v1.
+func repo(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.org", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.org")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %s", strings.Join(args, " "), out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "--quiet", "--initial-branch=main")
+	write("v1")
+	git("add", "main.tf")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1")
+	v1 := git("rev-parse", "HEAD")
+	write("v2")
+	git("commit", "--quiet", "-am", "v2")
+	return dir, v1
+}
+
+func TestGetter(t *testing.T) {
+	src, v1 := repo(t)
+
+	type want struct {
+		content string
+		err     bool
+	}
+
+	// Any environment variables cause repositories to be fetched by a Getter
+	// rather than by go-getter's git getter.
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+
+	cases := map[string]struct {
+		reason string
+		env    []string
+		refs   []string
+		depth  int
+		want   want
+	}{
+		"NoEnv": {
+			reason: "A repository should be fetched by go-getter's git getter if there are no environment variables.",
+			refs:   []string{"v1"},
+			want:   want{content: "v1"},
+		},
+		"DefaultBranch": {
+			reason: "A repository without a ref should be fetched at HEAD.",
+			env:    env,
+			refs:   []string{""},
+			want:   want{content: "v2"},
+		},
+		"Tag": {
+			reason: "A repository should be fetched at the supplied tag.",
+			env:    env,
+			refs:   []string{"v1"},
+			want:   want{content: "v1"},
+		},
+		"Commit": {
+			reason: "A repository should be fetched at the supplied commit.",
+			env:    env,
+			refs:   []string{v1[:7]},
+			want:   want{content: "v1"},
+		},
+		"ShallowTag": {
+			reason: "A shallow clone of a repository should be fetched at the supplied tag.",
+			env:    env,
+			refs:   []string{"v1"},
+			depth:  1,
+			want:   want{content: "v1"},
+		},
+		"ShallowCommit": {
+			reason: "A shallow clone of a repository cannot be fetched at a commit.",
+			env:    env,
+			refs:   []string{v1[:7]},
+			depth:  1,
+			want:   want{err: true},
+		},
+		"Update": {
+			reason: "A repository that was already fetched should be updated to the supplied ref.",
+			env:    env,
+			refs:   []string{"v1", "main"},
+			want:   want{content: "v2"},
+		},
+		"Env": {
+			reason: "Git should be run with the supplied environment variables.",
+			env:    []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=protocol.file.allow", "GIT_CONFIG_VALUE_0=never"},
+			refs:   []string{""},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "module")
+			var err error
+			for _, ref := range tc.refs {
+				q := url.Values{}
+				if ref != "" {
+					q.Set("ref", ref)
+				}
+				if tc.depth > 0 {
+					q.Set("depth", strconv.Itoa(tc.depth))
+				}
+				s := "git::file://" + src
+				if len(q) > 0 {
+					s += "?" + q.Encode()
+				}
+				c := getter.Client{
+					Ctx:     context.Background(),
+					Src:     s,
+					Dst:     dst,
+					Mode:    getter.ClientModeDir,
+					Getters: Getters(tc.env),
+				}
+				err = c.Get()
+			}
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Fatalf("\n%s\nc.Get(...): -want error, +got error:\n%s\n%v", tc.reason, diff, err)
+			}
+			if err != nil {
+				return
+			}
+			b, err := os.ReadFile(filepath.Join(dst, "main.tf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want.content, string(b)); diff != "" {
+				t.Errorf("\n%s\nc.Get(...): -want content, +got content:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
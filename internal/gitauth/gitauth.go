@@ -0,0 +1,129 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitauth configures git to authenticate to repositories using
+// per-Workspace credentials. Credentials are passed to each git process using
+// environment variables, rather than by configuring the environment of the
+// provider process, so that Workspaces using different credentials may be
+// reconciled concurrently.
+package gitauth
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+)
+
+// Filenames of credentials written by Write.
+const (
+	FilenameStore      = ".git-credentials"
+	FilenameSSHKey     = "git_ssh_key"
+	FilenameKnownHosts = "git_known_hosts"
+)
+
+// Error strings.
+const (
+	errMkdir         = "cannot create credentials directory"
+	errFmtWriteFile  = "cannot write %s"
+	errFmtRemoveFile = "cannot remove %s"
+)
+
+// Credentials used to authenticate to git repositories.
+type Credentials struct {
+	// Store is a git credential store, in the format read by git's store
+	// credential helper. It is used to authenticate to HTTPS repositories.
+	Store []byte
+
+	// SSHPrivateKey is used to authenticate to SSH repositories.
+	SSHPrivateKey []byte
+
+	// SSHKnownHosts are the known host keys of SSH repositories. Host keys
+	// are accepted the first time they're seen if no known hosts are
+	// supplied.
+	SSHKnownHosts []byte
+}
+
+// Key uniquely identifies the credentials, for example to key a cache of
+// modules fetched using them.
+func (c Credentials) Key() []byte {
+	return bytes.Join([][]byte{c.Store, c.SSHPrivateKey, c.SSHKnownHosts}, []byte{0})
+}
+
+// Write the supplied credentials to the supplied directory, returning the
+// environment variables that configure git to use them. Credentials that are
+// not supplied are removed from the directory, if they were previously
+// written.
+func Write(fs afero.Afero, dir string, c Credentials) ([]string, error) {
+	if err := fs.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, errMkdir)
+	}
+
+	var env []string
+
+	store := filepath.Join(dir, FilenameStore)
+	if err := write(fs, store, c.Store); err != nil {
+		return nil, err
+	}
+	if len(c.Store) > 0 {
+		env = append(env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=credential.helper",
+			"GIT_CONFIG_VALUE_0=store --file="+store,
+		)
+	}
+
+	key := filepath.Join(dir, FilenameSSHKey)
+	if err := write(fs, key, c.SSHPrivateKey); err != nil {
+		return nil, err
+	}
+	hosts := filepath.Join(dir, FilenameKnownHosts)
+	if len(c.SSHPrivateKey) == 0 || len(c.SSHKnownHosts) > 0 {
+		// Host keys that were accepted on first use are only kept while we
+		// don't have known hosts.
+		if err := write(fs, hosts, c.SSHKnownHosts); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.SSHPrivateKey) > 0 {
+		checking := "accept-new"
+		if len(c.SSHKnownHosts) > 0 {
+			checking = "yes"
+		}
+		env = append(env, "GIT_SSH_COMMAND=ssh -i "+key+" -o IdentitiesOnly=yes -o BatchMode=yes -o UserKnownHostsFile="+hosts+" -o StrictHostKeyChecking="+checking)
+	}
+
+	if len(env) > 0 {
+		env = append(env, "GIT_TERMINAL_PROMPT=0")
+	}
+	return env, nil
+}
+
+// write the supplied data to the supplied path, or remove it if there is no
+// data.
+func write(fs afero.Afero, path string, data []byte) error {
+	if len(data) == 0 {
+		if err := fs.Remove(path); resource.Ignore(os.IsNotExist, err) != nil {
+			return errors.Wrapf(err, errFmtRemoveFile, filepath.Base(path))
+		}
+		return nil
+	}
+	return errors.Wrapf(fs.WriteFile(path, data, 0600), errFmtWriteFile, filepath.Base(path))
+}
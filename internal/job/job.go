@@ -0,0 +1,442 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package job runs Terraform commands in Kubernetes Jobs, rather than in the
+// provider process. Each command runs in a pod of its own that mounts the
+// volume containing the working directories of all Workspaces, so that the
+// module, variables and credentials written by the provider are available to
+// it, and the state it writes is available to the provider.
+package job
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+
+	"github.com/upbound/provider-terraform/internal/terraform"
+)
+
+// Error strings.
+const (
+	errCreateJob     = "cannot create Terraform Job"
+	errCreateSecret  = "cannot create Terraform Job environment Secret"
+	errListPods      = "cannot list Terraform Job pods"
+	errStreamLogs    = "cannot stream Terraform Job logs"
+	errWaitJob       = "stopped waiting for Terraform Job"
+	errReviewAccess  = "cannot review the provider's permission to run Terraform Jobs"
+	errFmtNoAccess   = "the provider is not permitted to %s %s in namespace %q, which it needs to run Terraform Jobs"
+	errFmtServiceAcc = "service account %q may not run Terraform Jobs; the provider must be configured to allow it"
+	errFmtNotMounted = "working directory %q is not within the Job volume mount path %q"
+	errFmtPodFailed  = "Terraform Job pod cannot start: %s: %s"
+	errFmtJobFailed  = "Terraform Job failed: %s"
+)
+
+// Labels applied to Jobs, their pods, and their environment Secrets.
+const (
+	// LabelOperation is the Terraform command the Job runs, for example
+	// plan.
+	LabelOperation = "tf.upbound.io/operation"
+
+	// LabelWorkspace is the UID of the Workspace the Job runs for.
+	LabelWorkspace = "tf.upbound.io/workspace-uid"
+)
+
+// The name of the container that runs Terraform.
+const container = "terraform"
+
+// Pods whose containers are waiting for these reasons won't start without
+// intervention.
+var fatal = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// A Template configures the pods of the Jobs run for a Workspace.
+type Template struct {
+	// ServiceAccountName of the pods.
+	ServiceAccountName string
+
+	// Resources of the container that runs Terraform.
+	Resources corev1.ResourceRequirements
+
+	// NodeSelector of the pods.
+	NodeSelector map[string]string
+
+	// Tolerations of the pods.
+	Tolerations []corev1.Toleration
+}
+
+// A Launcher creates Runners that run Terraform in Jobs.
+type Launcher struct {
+	client    kubernetes.Interface
+	namespace string
+	image     string
+	claim     string
+	mountPath string
+	accounts  map[string]bool
+	poll      time.Duration
+	log       logging.Logger
+}
+
+// An Option configures a new Launcher.
+type Option func(l *Launcher)
+
+// WithPollInterval configures how often Jobs are checked for progress. The
+// default is two seconds.
+func WithPollInterval(d time.Duration) Option {
+	return func(l *Launcher) { l.poll = d }
+}
+
+// WithServiceAccounts configures the service accounts that Workspaces may ask
+// their Jobs' pods to run as. By default Workspaces may only use the default
+// service account of the namespace in which Jobs are created.
+func WithServiceAccounts(names ...string) Option {
+	return func(l *Launcher) {
+		for _, n := range names {
+			l.accounts[n] = true
+		}
+	}
+}
+
+// WithLogger configures the logger that will be used. Terraform's output is
+// logged at debug level as it's streamed. The default is a no-op logger that
+// never emits logs.
+func WithLogger(log logging.Logger) Option {
+	return func(l *Launcher) { l.log = log }
+}
+
+// NewLauncher returns a Launcher that creates Jobs in the supplied namespace.
+// Jobs run the supplied image, which must contain the Terraform binary at the
+// same path as the provider's image. The supplied PersistentVolumeClaim is
+// mounted at the supplied path, which must be the path at which the provider
+// mounts it.
+func NewLauncher(c kubernetes.Interface, namespace, image, claim, mountPath string, o ...Option) *Launcher {
+	l := &Launcher{
+		client:    c,
+		namespace: namespace,
+		image:     image,
+		claim:     claim,
+		mountPath: mountPath,
+		accounts:  map[string]bool{},
+		poll:      2 * time.Second,
+		log:       logging.NewNopLogger(),
+	}
+	for _, fn := range o {
+		fn(l)
+	}
+	return l
+}
+
+// Permitted returns an error if the provider isn't permitted to do everything
+// it needs to do to run Terraform in Jobs.
+func (l *Launcher) Permitted(ctx context.Context) error {
+	required := []authorizationv1.ResourceAttributes{
+		{Verb: "create", Group: batchv1.GroupName, Resource: "jobs"},
+		{Verb: "get", Group: batchv1.GroupName, Resource: "jobs"},
+		{Verb: "delete", Group: batchv1.GroupName, Resource: "jobs"},
+		{Verb: "create", Resource: "secrets"},
+		{Verb: "list", Resource: "pods"},
+		{Verb: "get", Resource: "pods", Subresource: "log"},
+	}
+	for _, ra := range required {
+		ra.Namespace = l.namespace
+		rv, err := l.client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &ra},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, errReviewAccess)
+		}
+		if !rv.Status.Allowed {
+			res := ra.Resource
+			if ra.Subresource != "" {
+				res += "/" + ra.Subresource
+			}
+			return errors.Errorf(errFmtNoAccess, ra.Verb, res, l.namespace)
+		}
+	}
+	return nil
+}
+
+// Runner returns a Runner that runs Terraform in Jobs whose pods are
+// configured by the supplied template. The supplied labels are applied to
+// the Jobs. It returns an error if the template asks for a service account
+// that Jobs may not run as.
+func (l *Launcher) Runner(t Template, labels map[string]string) (*Runner, error) {
+	if t.ServiceAccountName != "" && !l.accounts[t.ServiceAccountName] {
+		return nil, errors.Errorf(errFmtServiceAcc, t.ServiceAccountName)
+	}
+	return &Runner{Launcher: l, template: t, labels: labels}, nil
+}
+
+// A Runner runs Terraform commands in Jobs.
+type Runner struct {
+	*Launcher
+	template Template
+	labels   map[string]string
+}
+
+// Run the supplied Terraform command in a Job, and return its output. The Job
+// is deleted once it finishes, or if the supplied context is done first.
+// Terraform's standard and error output can't be told apart, so both are
+// returned, including in the *terraform.ExitError returned if Terraform exits
+// with a non-zero exit code.
+func (r *Runner) Run(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	j, s, err := r.job(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err = r.client.BatchV1().Jobs(r.namespace).Create(ctx, j, metav1.CreateOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, errCreateJob)
+	}
+	defer r.delete(j.GetName())
+
+	// The Secret is owned by the Job, so that it's deleted with the Job. The
+	// Job's pod won't start until the Secret exists. The owner reference
+	// doesn't block deletion of the Job, which would require permission to
+	// update the Job's finalizers.
+	s.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: batchv1.SchemeGroupVersion.String(),
+		Kind:       "Job",
+		Name:       j.GetName(),
+		UID:        j.GetUID(),
+		Controller: ptr.To(true),
+	}})
+	if _, err := r.client.CoreV1().Secrets(r.namespace).Create(ctx, s, metav1.CreateOptions{}); err != nil {
+		return nil, errors.Wrap(err, errCreateSecret)
+	}
+
+	pod, err := r.wait(ctx, j.GetName(), started)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.stream(ctx, pod)
+	if err != nil {
+		return nil, err
+	}
+	pod, err = r.wait(ctx, j.GetName(), terminated)
+	if err != nil {
+		return nil, err
+	}
+
+	if code := int(state(pod).Terminated.ExitCode); code != 0 {
+		return out, &terraform.ExitError{Code: code, Stderr: out}
+	}
+	return out, nil
+}
+
+// job returns a Job that runs the supplied command, and the Secret containing
+// its environment.
+func (r *Runner) job(cmd *exec.Cmd) (*batchv1.Job, *corev1.Secret, error) {
+	if !within(r.mountPath, cmd.Dir) {
+		return nil, nil, errors.Errorf(errFmtNotMounted, cmd.Dir, r.mountPath)
+	}
+
+	op := "terraform"
+	if len(cmd.Args) > 1 {
+		op = cmd.Args[1]
+	}
+	name := "tf-" + op + "-" + rand.String(8)
+
+	labels := map[string]string{LabelOperation: op}
+	for k, v := range r.labels {
+		labels[k] = v
+	}
+
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: r.namespace, Name: name, Labels: labels},
+		StringData: env(cmd),
+	}
+
+	j := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: r.namespace, Name: name, Labels: labels},
+		Spec: batchv1.JobSpec{
+			// Terraform isn't retried by the Job. The Workspace is
+			// reconciled again instead.
+			BackoffLimit: ptr.To(int32(0)),
+			// Jobs are deleted once they finish. This is a backstop in case
+			// the provider stops before it can delete them.
+			TTLSecondsAfterFinished: ptr.To(int32(3600)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: r.template.ServiceAccountName,
+					NodeSelector:       r.template.NodeSelector,
+					Tolerations:        r.template.Tolerations,
+					Containers: []corev1.Container{{
+						Name:         container,
+						Image:        r.image,
+						Command:      []string{cmd.Path},
+						Args:         cmd.Args[1:],
+						WorkingDir:   cmd.Dir,
+						Resources:    r.template.Resources,
+						EnvFrom:      []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}}}},
+						VolumeMounts: []corev1.VolumeMount{{Name: "workdir", MountPath: r.mountPath}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name:         "workdir",
+						VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: r.claim}},
+					}},
+				},
+			},
+		},
+	}
+	return j, s, nil
+}
+
+// wait until the pod of the supplied Job satisfies the supplied condition,
+// and return it.
+func (r *Runner) wait(ctx context.Context, name string, cond func(p *corev1.Pod) bool) (*corev1.Pod, error) {
+	t := time.NewTicker(r.poll)
+	defer t.Stop()
+	for {
+		l, err := r.client.CoreV1().Pods(r.namespace).List(ctx, metav1.ListOptions{LabelSelector: batchv1.JobNameLabel + "=" + name})
+		if err != nil {
+			return nil, errors.Wrap(err, errListPods)
+		}
+		for i := range l.Items {
+			p := &l.Items[i]
+			if cond(p) {
+				return p, nil
+			}
+			if w := state(p).Waiting; w != nil && fatal[w.Reason] {
+				return nil, errors.Errorf(errFmtPodFailed, w.Reason, w.Message)
+			}
+		}
+		if err := r.failed(ctx, name); err != nil {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), errWaitJob)
+		case <-t.C:
+		}
+	}
+}
+
+// failed returns an error if the supplied Job failed without its pod running
+// Terraform, for example because it exceeded its deadline.
+func (r *Runner) failed(ctx context.Context, name string) error {
+	j, err := r.client.BatchV1().Jobs(r.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		// We'll find out whether the Job failed next time.
+		return nil //nolint:nilerr
+	}
+	for _, c := range j.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return errors.Errorf(errFmtJobFailed, c.Message)
+		}
+	}
+	return nil
+}
+
+// stream the output of Terraform until it exits, logging each line.
+func (r *Runner) stream(ctx context.Context, pod *corev1.Pod) ([]byte, error) {
+	rc, err := r.client.CoreV1().Pods(r.namespace).GetLogs(pod.GetName(), &corev1.PodLogOptions{Container: container, Follow: true}).Stream(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, errStreamLogs)
+	}
+	defer rc.Close() //nolint:errcheck // Only reading.
+
+	out := &bytes.Buffer{}
+	sc := bufio.NewScanner(rc)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		r.log.Debug(sc.Text(), "job", pod.GetLabels()[batchv1.JobNameLabel])
+		out.Write(sc.Bytes())
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), errors.Wrap(sc.Err(), errStreamLogs)
+}
+
+// delete the supplied Job and its pod. The Job is deleted even if the context
+// of the command it ran is done, so that Terraform is stopped.
+func (r *Runner) delete(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := r.client.BatchV1().Jobs(r.namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: ptr.To(metav1.DeletePropagationBackground)})
+	if err != nil {
+		r.log.Info("Cannot delete Terraform Job", "job", name, "error", err)
+	}
+}
+
+// env returns the environment of the supplied command. Variables the command
+// inherited unchanged from the provider process aren't included, because they
+// describe the provider's pod rather than Terraform's configuration. Those
+// that configure Terraform are the exception.
+func env(cmd *exec.Cmd) map[string]string {
+	inherited := map[string]bool{}
+	for _, e := range os.Environ() {
+		inherited[e] = true
+	}
+	environ := cmd.Env
+	if environ == nil {
+		environ = os.Environ()
+	}
+
+	out := map[string]string{}
+	for _, e := range environ {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok || (inherited[e] && !strings.HasPrefix(k, "TF_")) {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func within(parent, dir string) bool {
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func state(p *corev1.Pod) corev1.ContainerState {
+	for _, s := range p.Status.ContainerStatuses {
+		if s.Name == container {
+			return s.State
+		}
+	}
+	return corev1.ContainerState{}
+}
+
+func started(p *corev1.Pod) bool {
+	s := state(p)
+	return s.Running != nil || s.Terminated != nil
+}
+
+func terminated(p *corev1.Pod) bool {
+	return state(p).Terminated != nil
+}
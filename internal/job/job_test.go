@@ -0,0 +1,248 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	"github.com/upbound/provider-terraform/internal/terraform"
+)
+
+func TestRun(t *testing.T) {
+	type want struct {
+		out []byte
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		dir    string
+		state  corev1.ContainerState
+		want   want
+	}{
+		"Succeeded": {
+			reason: "We should return Terraform's output if it exits successfully.",
+			dir:    "/tf/uid",
+			state:  corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}},
+			want:   want{out: []byte("fake logs\n")},
+		},
+		"ExitCode": {
+			reason: "We should return an ExitError containing Terraform's output if it exits with a non-zero exit code.",
+			dir:    "/tf/uid",
+			state:  corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2}},
+			want: want{
+				out: []byte("fake logs\n"),
+				err: &terraform.ExitError{Code: 2, Stderr: []byte("fake logs\n")},
+			},
+		},
+		"ImagePullBackOff": {
+			reason: "We should return an error if the Job's pod can't start.",
+			dir:    "/tf/uid",
+			state:  corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "boom"}},
+			want:   want{err: errors.Errorf(errFmtPodFailed, "ImagePullBackOff", "boom")},
+		},
+		"NotMounted": {
+			reason: "We should return an error if the working directory isn't on the Job's volume.",
+			dir:    "/tmp/tf/uid",
+			want:   want{err: errors.Errorf(errFmtNotMounted, "/tmp/tf/uid", "/tf")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := fake.NewClientset()
+			c.PrependReactor("create", "jobs", func(a k8stesting.Action) (bool, runtime.Object, error) {
+				j := a.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: j.GetNamespace(),
+						Name:      j.GetName() + "-pod",
+						Labels:    map[string]string{batchv1.JobNameLabel: j.GetName()},
+					},
+					Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: container, State: tc.state}}},
+				}
+				return false, nil, c.Tracker().Add(pod)
+			})
+			c.PrependReactor("create", "secrets", func(a k8stesting.Action) (bool, runtime.Object, error) {
+				// Blocking deletion of the Job would require permission to
+				// update its finalizers.
+				for _, ref := range a.(k8stesting.CreateAction).GetObject().(*corev1.Secret).GetOwnerReferences() {
+					if ptr.Deref(ref.BlockOwnerDeletion, false) {
+						return true, nil, errors.New("the Secret's owner reference must not block deletion of the Job")
+					}
+				}
+				return false, nil, nil
+			})
+
+			r, err := NewLauncher(c, "ns", "image", "claim", "/tf", WithPollInterval(time.Millisecond)).Runner(Template{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd := exec.Command("terraform", "plan", "-no-color")
+			cmd.Dir = tc.dir
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			out, err := r.Run(ctx, cmd)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nr.Run(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.out, out); diff != "" {
+				t.Errorf("\n%s\nr.Run(...): -want output, +got output:\n%s", tc.reason, diff)
+			}
+
+			// Jobs must not outlive the command they ran.
+			jobs, err := c.BatchV1().Jobs("ns").List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(0, len(jobs.Items)); diff != "" {
+				t.Errorf("\n%s\nr.Run(...): -want Jobs, +got Jobs:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunner(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		accounts []string
+		t        Template
+		want     error
+	}{
+		"DefaultServiceAccount": {
+			reason: "Jobs should always be allowed to run as the default service account.",
+		},
+		"AllowedServiceAccount": {
+			reason:   "Jobs should be allowed to run as service accounts the provider allows.",
+			accounts: []string{"terraform"},
+			t:        Template{ServiceAccountName: "terraform"},
+		},
+		"DisallowedServiceAccount": {
+			reason:   "Jobs should not be allowed to run as service accounts the provider doesn't allow.",
+			accounts: []string{"terraform"},
+			t:        Template{ServiceAccountName: "provider-terraform"},
+			want:     errors.Errorf(errFmtServiceAcc, "provider-terraform"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			l := NewLauncher(fake.NewClientset(), "ns", "image", "claim", "/tf", WithServiceAccounts(tc.accounts...))
+			_, err := l.Runner(tc.t, nil)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nl.Runner(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPermitted(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		denied string
+		want   error
+	}{
+		"Permitted": {
+			reason: "We should return nil if the provider may do everything it needs to run Jobs.",
+		},
+		"NotPermitted": {
+			reason: "We should return an error naming the first thing the provider may not do.",
+			denied: "log",
+			want:   errors.Errorf(errFmtNoAccess, "get", "pods/log", "ns"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := fake.NewClientset()
+			c.PrependReactor("create", "selfsubjectaccessreviews", func(a k8stesting.Action) (bool, runtime.Object, error) {
+				rv := a.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				rv.Status.Allowed = tc.denied == "" || rv.Spec.ResourceAttributes.Subresource != tc.denied
+				return true, rv, nil
+			})
+			err := NewLauncher(c, "ns", "image", "claim", "/tf").Permitted(context.Background())
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nl.Permitted(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("TF_PLUGIN_CACHE_DIR", "/tf/plugin-cache")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+
+	cases := map[string]struct {
+		reason string
+		env    []string
+		want   map[string]string
+	}{
+		"Inherited": {
+			reason: "Only variables that configure Terraform should be inherited from the provider process.",
+			want:   map[string]string{"TF_PLUGIN_CACHE_DIR": "/tf/plugin-cache"},
+		},
+		"Explicit": {
+			reason: "Variables supplied by the Workspace should be included, even if they override inherited variables.",
+			env: []string{
+				"TF_PLUGIN_CACHE_DIR=/tf/plugin-cache",
+				"KUBERNETES_SERVICE_HOST=10.0.0.1",
+				"AWS_REGION=us-east-1",
+				"KUBERNETES_SERVICE_HOST=10.0.0.2",
+			},
+			want: map[string]string{
+				"TF_PLUGIN_CACHE_DIR":     "/tf/plugin-cache",
+				"AWS_REGION":              "us-east-1",
+				"KUBERNETES_SERVICE_HOST": "10.0.0.2",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cmd := exec.Command("terraform")
+			cmd.Env = tc.env
+			got := env(cmd)
+			for k := range got {
+				// Ignore Terraform variables set by whatever runs the test.
+				if _, ok := tc.want[k]; !ok && strings.HasPrefix(k, "TF_") {
+					delete(got, k)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nenv(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,482 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package modcache caches Terraform modules that are shared by Workspaces.
+package modcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/upbound/provider-terraform/internal/verify"
+	"github.com/upbound/provider-terraform/internal/workdir"
+)
+
+// Error strings.
+const (
+	errMkdir      = "cannot make module cache directory"
+	errFetch      = "cannot fetch module"
+	errDigest     = "cannot compute module digest"
+	errStore      = "cannot store module in cache"
+	errCopy       = "cannot copy module from cache"
+	errReadEntry  = "cannot read module cache entry"
+	errWriteEntry = "cannot write module cache entry"
+	errEvict      = "cannot evict modules from cache"
+	errEvicted    = "module was evicted from cache before it could be copied"
+)
+
+const (
+	dirEntries = "entries"
+	dirModules = "modules"
+	dirTmp     = "tmp"
+)
+
+// A FetchFn fetches a module into the supplied directory.
+type FetchFn func(ctx context.Context, dir string) error
+
+// A Cache of Terraform modules. Modules are stored once per distinct content,
+// addressed by the digest of their manifest per the verify package. Entries
+// map the source of a module, and the credentials used to fetch it, to the
+// digest of the module it resolved to.
+type Cache struct {
+	fs       afero.Afero
+	dir      string
+	ttl      time.Duration
+	maxSize  int64
+	interval time.Duration
+	timeout  time.Duration
+	log      logging.Logger
+	now      func() time.Time
+
+	// mu protects the bookkeeping below, and the entries and modules
+	// directories. It's never held while a module is fetched or copied.
+	mu sync.Mutex
+	// inUse counts the Gets copying each module, by digest. Modules that are
+	// in use are never evicted.
+	inUse map[string]int
+	// fetching contains the temporary directories modules are being fetched
+	// to. They're never evicted.
+	fetching map[string]bool
+
+	sf singleflight.Group
+}
+
+// An Option configures a Cache.
+type Option func(c *Cache)
+
+// WithFs configures the afero filesystem implementation in which modules are
+// cached. The default is the real operating system filesystem.
+func WithFs(fs afero.Afero) Option {
+	return func(c *Cache) { c.fs = fs }
+}
+
+// WithTTL configures how long a cached module may be used before its source
+// is fetched again. The default is one minute.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) { c.ttl = ttl }
+}
+
+// WithMaxSize configures the size in bytes that the cache may grow to before
+// the least recently used modules are evicted. The default is 5GiB.
+func WithMaxSize(bytes int64) Option {
+	return func(c *Cache) { c.maxSize = bytes }
+}
+
+// WithInterval configures how often modules are evicted from the cache. The
+// default interval is ten minutes.
+func WithInterval(i time.Duration) Option {
+	return func(c *Cache) { c.interval = i }
+}
+
+// WithFetchTimeout configures how long a module may take to fetch. Fetches
+// are shared by concurrent Gets, so they're not cancelled when the context of
+// the Get that started them is. The default is ten minutes.
+func WithFetchTimeout(t time.Duration) Option {
+	return func(c *Cache) { c.timeout = t }
+}
+
+// WithLogger configures the logger that will be used. The default is a no-op
+// logger that never emits logs.
+func WithLogger(l logging.Logger) Option {
+	return func(c *Cache) { c.log = l }
+}
+
+// New returns a cache of Terraform modules in the supplied directory.
+func New(dir string, o ...Option) *Cache {
+	c := &Cache{
+		fs:       afero.Afero{Fs: afero.NewOsFs()},
+		dir:      dir,
+		ttl:      1 * time.Minute,
+		maxSize:  5 << 30,
+		interval: 10 * time.Minute,
+		timeout:  10 * time.Minute,
+		log:      logging.NewNopLogger(),
+		now:      time.Now,
+		inUse:    map[string]int{},
+		fetching: map[string]bool{},
+	}
+	for _, fn := range o {
+		fn(c)
+	}
+	return c
+}
+
+type entry struct {
+	Source  string    `json:"source"`
+	Digest  string    `json:"digest"`
+	Fetched time.Time `json:"fetched"`
+	Used    time.Time `json:"used"`
+}
+
+// key returns the key of the entry for the supplied source and credentials.
+// Credentials are part of the key so that a module fetched using credentials
+// is never used by a Workspace that doesn't have them.
+func key(src string, creds []byte) string {
+	c := sha256.Sum256(creds)
+	k := sha256.Sum256([]byte(src + "\x00" + hex.EncodeToString(c[:])))
+	return hex.EncodeToString(k[:])
+}
+
+func (c *Cache) entryPath(k string) string {
+	return filepath.Join(c.dir, dirEntries, k+".json")
+}
+
+func (c *Cache) modulePath(digest string) string {
+	return filepath.Join(c.dir, dirModules, strings.TrimPrefix(digest, verify.DigestAlgorithm))
+}
+
+// Get the module with the supplied source into the supplied directory,
+// returning its digest and the paths of its files relative to the directory.
+// The module is fetched using the supplied function unless an entry for the
+// supplied source and credentials was fetched within the TTL. Concurrent
+// fetches of the same source and credentials are deduplicated.
+func (c *Cache) Get(ctx context.Context, src string, creds []byte, dir string, fetch FetchFn) (string, []string, error) {
+	k := key(src, creds)
+
+	c.mu.Lock()
+	e, err := c.readEntry(k)
+	hit := err == nil && c.fresh(e)
+	if hit {
+		c.inUse[e.Digest]++
+	}
+	c.mu.Unlock()
+
+	if !hit {
+		// The fetch is shared with any concurrent Gets, so it's not tied to
+		// the context of this one. We stop waiting for it if ours is done.
+		ch := c.sf.DoChan(k, func() (any, error) {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+			defer cancel()
+			return c.populate(ctx, k, src, fetch)
+		})
+		select {
+		case <-ctx.Done():
+			return "", nil, errors.Wrap(ctx.Err(), errFetch)
+		case r := <-ch:
+			if r.Err != nil {
+				return "", nil, r.Err
+			}
+			e = r.Val.(*entry) //nolint:forcetypeassert // populate always returns an *entry.
+		}
+
+		c.mu.Lock()
+		ok, err := c.fs.DirExists(c.modulePath(e.Digest))
+		if ok && err == nil {
+			c.inUse[e.Digest]++
+		}
+		c.mu.Unlock()
+		if !ok || err != nil {
+			return "", nil, errors.New(errEvicted)
+		}
+	}
+	defer c.release(e.Digest)
+
+	files, err := workdir.Copy(c.fs, c.modulePath(e.Digest), dir)
+	if err != nil {
+		return "", nil, errors.Wrap(err, errCopy)
+	}
+
+	used := *e
+	used.Used = c.now()
+	if err := c.writeEntry(k, &used); err != nil {
+		// Failing to record use only makes the module more likely to be evicted.
+		c.log.Debug("Cannot record use of cached module", "source", src, "error", err)
+	}
+	return e.Digest, files, nil
+}
+
+func (c *Cache) release(digest string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inUse[digest]--; c.inUse[digest] <= 0 {
+		delete(c.inUse, digest)
+	}
+}
+
+func (c *Cache) fresh(e *entry) bool {
+	if c.now().Sub(e.Fetched) > c.ttl {
+		return false
+	}
+	ok, err := c.fs.DirExists(c.modulePath(e.Digest))
+	return ok && err == nil
+}
+
+func (c *Cache) populate(ctx context.Context, k, src string, fetch FetchFn) (*entry, error) {
+	c.mu.Lock()
+	tmp, err := c.tempDir()
+	if err == nil {
+		c.fetching[filepath.Base(tmp)] = true
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.fetching, filepath.Base(tmp))
+		_ = c.fs.RemoveAll(tmp)
+	}()
+
+	// Some getters, like git, expect to fetch to a directory that doesn't
+	// exist yet.
+	mod := filepath.Join(tmp, "module")
+	if err := fetch(ctx, mod); err != nil {
+		return nil, errors.Wrap(err, errFetch)
+	}
+	// The module's git metadata is neither needed by Terraform, nor part of
+	// its content.
+	if err := c.fs.RemoveAll(filepath.Join(mod, ".git")); err != nil {
+		return nil, errors.Wrap(err, errStore)
+	}
+
+	m, err := verify.Manifest(c.fs, mod)
+	if err != nil {
+		return nil, errors.Wrap(err, errDigest)
+	}
+	d := verify.Digest(m)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.modulePath(d)
+	exists, err := c.fs.DirExists(p)
+	if err != nil {
+		return nil, errors.Wrap(err, errStore)
+	}
+	if !exists {
+		if err := c.fs.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			return nil, errors.Wrap(err, errMkdir)
+		}
+		if err := c.fs.Rename(mod, p); err != nil {
+			return nil, errors.Wrap(err, errStore)
+		}
+	}
+
+	now := c.now()
+	e := &entry{Source: src, Digest: d, Fetched: now, Used: now}
+	return e, c.writeEntry(k, e)
+}
+
+func (c *Cache) tempDir() (string, error) {
+	if err := c.fs.MkdirAll(filepath.Join(c.dir, dirTmp), 0700); err != nil {
+		return "", errors.Wrap(err, errMkdir)
+	}
+	tmp, err := c.fs.TempDir(filepath.Join(c.dir, dirTmp), "module")
+	return tmp, errors.Wrap(err, errMkdir)
+}
+
+func (c *Cache) readEntry(k string) (*entry, error) {
+	b, err := c.fs.ReadFile(c.entryPath(k))
+	if err != nil {
+		return nil, errors.Wrap(err, errReadEntry)
+	}
+	e := &entry{}
+	return e, errors.Wrap(json.Unmarshal(b, e), errReadEntry)
+}
+
+// writeEntry atomically writes the supplied entry, so that concurrent readers
+// never observe a partially written entry.
+func (c *Cache) writeEntry(k string, e *entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, errWriteEntry)
+	}
+	if err := c.fs.MkdirAll(filepath.Join(c.dir, dirEntries), 0700); err != nil {
+		return errors.Wrap(err, errWriteEntry)
+	}
+	f, err := c.fs.TempFile(filepath.Join(c.dir, dirEntries), k+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, errWriteEntry)
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = c.fs.Rename(f.Name(), c.entryPath(k))
+	}
+	if err != nil {
+		_ = c.fs.Remove(f.Name())
+		return errors.Wrap(err, errWriteEntry)
+	}
+	return nil
+}
+
+// Run evicts modules from the cache periodically. Blocks until the supplied
+// context is done.
+func (c *Cache) Run(ctx context.Context) error {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			if err := c.Evict(); err != nil {
+				c.log.Info("Module cache eviction failed", "error", err)
+			}
+		}
+	}
+}
+
+// Evict entries that were fetched longer ago than the TTL, and modules that
+// are no longer referenced by any entry. If the cache is still larger than
+// its maximum size the least recently used entries, and their modules, are
+// evicted until it is not. Modules that are being fetched or copied are never
+// evicted.
+func (c *Cache) Evict() error { //nolint:gocyclo // Easier to follow as a single function.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Remove temporary directories left behind by fetches that didn't clean
+	// up after themselves, for example because the provider was restarted.
+	fis, err := c.fs.ReadDir(filepath.Join(c.dir, dirTmp))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, errEvict)
+	}
+	for _, fi := range fis {
+		if c.fetching[fi.Name()] {
+			continue
+		}
+		if err := c.fs.RemoveAll(filepath.Join(c.dir, dirTmp, fi.Name())); err != nil {
+			return errors.Wrap(err, errEvict)
+		}
+	}
+
+	type keyed struct {
+		key string
+		*entry
+	}
+	entries := []keyed{}
+	fis, err = c.fs.ReadDir(filepath.Join(c.dir, dirEntries))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, errEvict)
+	}
+	for _, fi := range fis {
+		k, ok := strings.CutSuffix(fi.Name(), ".json")
+		if !ok {
+			// A stray temporary file.
+			_ = c.fs.Remove(filepath.Join(c.dir, dirEntries, fi.Name()))
+			continue
+		}
+		e, err := c.readEntry(k)
+		if err != nil || c.now().Sub(e.Fetched) > c.ttl {
+			if err := c.fs.Remove(c.entryPath(k)); err != nil {
+				return errors.Wrap(err, errEvict)
+			}
+			continue
+		}
+		entries = append(entries, keyed{key: k, entry: e})
+	}
+
+	refs := map[string]int{}
+	for _, e := range entries {
+		refs[strings.TrimPrefix(e.Digest, verify.DigestAlgorithm)]++
+	}
+
+	sizes := map[string]int64{}
+	var total int64
+	fis, err = c.fs.ReadDir(filepath.Join(c.dir, dirModules))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, errEvict)
+	}
+	for _, fi := range fis {
+		p := filepath.Join(c.dir, dirModules, fi.Name())
+		if refs[fi.Name()] == 0 && c.inUse[verify.DigestAlgorithm+fi.Name()] == 0 {
+			if err := c.fs.RemoveAll(p); err != nil {
+				return errors.Wrap(err, errEvict)
+			}
+			continue
+		}
+		s, err := c.size(p)
+		if err != nil {
+			return errors.Wrap(err, errEvict)
+		}
+		sizes[fi.Name()] = s
+		total += s
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Used.Before(entries[j].Used) })
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if c.inUse[e.Digest] > 0 {
+			continue
+		}
+		if err := c.fs.Remove(c.entryPath(e.key)); err != nil {
+			return errors.Wrap(err, errEvict)
+		}
+		d := strings.TrimPrefix(e.Digest, verify.DigestAlgorithm)
+		if refs[d]--; refs[d] > 0 {
+			continue
+		}
+		if err := c.fs.RemoveAll(filepath.Join(c.dir, dirModules, d)); err != nil {
+			return errors.Wrap(err, errEvict)
+		}
+		total -= sizes[d]
+	}
+
+	return nil
+}
+
+func (c *Cache) size(dir string) (int64, error) {
+	var s int64
+	err := c.fs.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			s += info.Size()
+		}
+		return nil
+	})
+	return s, err
+}
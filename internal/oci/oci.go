@@ -0,0 +1,263 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oci pulls Terraform modules packaged as OCI artifacts.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+
+	"github.com/upbound/provider-terraform/internal/workdir"
+)
+
+// Error strings.
+const (
+	errParseRef          = "cannot parse OCI artifact reference"
+	errParseDockerConfig = "cannot parse Docker config JSON"
+	errFetchManifest     = "cannot fetch OCI artifact manifest"
+	errParseManifest     = "cannot parse OCI artifact manifest"
+	errFetchLayer        = "cannot fetch OCI artifact layer"
+	errExtractLayer      = "cannot extract OCI artifact layer"
+	errWriteLayer        = "cannot write OCI artifact layer"
+	errNoModule          = "OCI artifact has no layers that contain Terraform module files"
+	errFmtIndex          = "OCI artifact reference resolves to an image index of media type %q, not to a manifest"
+)
+
+// Media types of image indexes, which reference a manifest per platform
+// rather than layers.
+var indexMediaTypes = map[string]bool{
+	ocispec.MediaTypeImageIndex:                                 true,
+	"application/vnd.docker.distribution.manifest.list.v2+json": true,
+}
+
+// AnnotationUnpack is set by ORAS on layers that are tarballs of directories.
+const AnnotationUnpack = "io.deis.oras.content.unpack"
+
+type pullOptions struct {
+	client *http.Client
+	creds  map[string]auth.Credential
+	pulled string
+}
+
+// A PullOption configures how an OCI artifact is pulled.
+type PullOption func(o *pullOptions) error
+
+// WithHTTPClient configures the HTTP client used to access OCI registries.
+// The default is a client that retries transient errors.
+func WithHTTPClient(c *http.Client) PullOption {
+	return func(o *pullOptions) error {
+		o.client = c
+		return nil
+	}
+}
+
+// WithPulledDigest configures the digest of the artifact that was last pulled
+// into the directory. The artifact isn't pulled again if its digest is
+// unchanged.
+func WithPulledDigest(d string) PullOption {
+	return func(o *pullOptions) error {
+		o.pulled = d
+		return nil
+	}
+}
+
+// WithDockerConfigJSON configures credentials for OCI registries, supplied in
+// the format of a kubernetes.io/dockerconfigjson Secret.
+func WithDockerConfigJSON(data []byte) PullOption {
+	return func(o *pullOptions) error {
+		cfg := struct {
+			Auths map[string]struct {
+				Username      string `json:"username"`
+				Password      string `json:"password"`
+				Auth          string `json:"auth"`
+				IdentityToken string `json:"identitytoken"`
+				RegistryToken string `json:"registrytoken"`
+			} `json:"auths"`
+		}{}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return errors.Wrap(err, errParseDockerConfig)
+		}
+		for host, a := range cfg.Auths {
+			c := auth.Credential{
+				Username:     a.Username,
+				Password:     a.Password,
+				RefreshToken: a.IdentityToken,
+				AccessToken:  a.RegistryToken,
+			}
+			if a.Auth != "" {
+				d, err := base64.StdEncoding.DecodeString(a.Auth)
+				if err != nil {
+					return errors.Wrap(err, errParseDockerConfig)
+				}
+				c.Username, c.Password, _ = strings.Cut(string(d), ":")
+			}
+			o.creds[registryHost(host)] = c
+		}
+		return nil
+	}
+}
+
+// registryHost returns the host of a Docker config auths entry, which may be
+// a URL such as https://index.docker.io/v1/.
+func registryHost(s string) string {
+	s = strings.TrimPrefix(s, "https://")
+	s = strings.TrimPrefix(s, "http://")
+	s, _, _ = strings.Cut(s, "/")
+	if s == "index.docker.io" {
+		// Traffic targeting docker.io is redirected to this host.
+		return "registry-1.docker.io"
+	}
+	return s
+}
+
+// Pull the OCI artifact with the supplied reference into the supplied
+// directory, returning the digest of its manifest and the paths of the files
+// that were written, relative to the directory. The reference may be prefixed
+// with oci://, and may specify a tag or a digest. Layers that are gzipped
+// tarballs are extracted into the directory, while any other layer that has a
+// title annotation is written to a file with that name, per the layout used
+// by ORAS. No files are written if the artifact's digest is the pulled digest.
+func Pull(ctx context.Context, fs afero.Afero, ref, dir string, o ...PullOption) (string, []string, error) {
+	po := &pullOptions{client: retry.DefaultClient, creds: map[string]auth.Credential{}}
+	for _, fn := range o {
+		if err := fn(po); err != nil {
+			return "", nil, err
+		}
+	}
+
+	r, err := registry.ParseReference(strings.TrimPrefix(ref, "oci://"))
+	if err != nil {
+		return "", nil, errors.Wrap(err, errParseRef)
+	}
+	repo, err := remote.NewRepository(r.Registry + "/" + r.Repository)
+	if err != nil {
+		return "", nil, errors.Wrap(err, errParseRef)
+	}
+	repo.Client = &auth.Client{
+		Client: po.client,
+		Cache:  auth.NewCache(),
+		Credential: func(_ context.Context, host string) (auth.Credential, error) {
+			return po.creds[host], nil
+		},
+	}
+
+	desc, rc, err := repo.FetchReference(ctx, r.ReferenceOrDefault())
+	if err != nil {
+		return "", nil, errors.Wrap(err, errFetchManifest)
+	}
+	defer rc.Close() //nolint:errcheck // Only reads from rc.
+	if indexMediaTypes[desc.MediaType] {
+		return "", nil, errors.Errorf(errFmtIndex, desc.MediaType)
+	}
+	if po.pulled != "" && desc.Digest.String() == po.pulled {
+		return po.pulled, nil, nil
+	}
+	b, err := content.ReadAll(rc, desc)
+	if err != nil {
+		return "", nil, errors.Wrap(err, errFetchManifest)
+	}
+	m := ocispec.Manifest{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return "", nil, errors.Wrap(err, errParseManifest)
+	}
+	if indexMediaTypes[m.MediaType] {
+		return "", nil, errors.Errorf(errFmtIndex, m.MediaType)
+	}
+
+	files, err := pullLayers(ctx, fs, repo, m.Layers, dir)
+	if err != nil {
+		return "", nil, err
+	}
+	return desc.Digest.String(), files, nil
+}
+
+func pullLayers(ctx context.Context, fs afero.Afero, repo *remote.Repository, layers []ocispec.Descriptor, dir string) ([]string, error) {
+	var files []string
+	for _, l := range layers {
+		f, err := pullLayer(ctx, fs, repo, l, dir)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f...)
+	}
+	if len(files) == 0 {
+		return nil, errors.New(errNoModule)
+	}
+	return files, nil
+}
+
+func pullLayer(ctx context.Context, fs afero.Afero, repo *remote.Repository, l ocispec.Descriptor, dir string) ([]string, error) {
+	title := l.Annotations[ocispec.AnnotationTitle]
+	tarball := isTarball(l)
+	if !tarball && title == "" {
+		// We don't know what to do with this layer.
+		return nil, nil
+	}
+
+	rc, err := repo.Fetch(ctx, l)
+	if err != nil {
+		return nil, errors.Wrap(err, errFetchLayer)
+	}
+	defer rc.Close() //nolint:errcheck // Only reads from rc.
+	b, err := content.ReadAll(rc, l)
+	if err != nil {
+		return nil, errors.Wrap(err, errFetchLayer)
+	}
+
+	if tarball {
+		files, err := workdir.Untar(fs, bytes.NewReader(b), dir)
+		return files, errors.Wrap(err, errExtractLayer)
+	}
+
+	p, err := workdir.SafeJoin(dir, title)
+	if err != nil {
+		return nil, errors.Wrap(err, errWriteLayer)
+	}
+	if err := fs.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return nil, errors.Wrap(err, errWriteLayer)
+	}
+	if err := fs.WriteFile(p, b, 0600); err != nil {
+		return nil, errors.Wrap(err, errWriteLayer)
+	}
+	return []string{filepath.Clean(title)}, nil
+}
+
+func isTarball(l ocispec.Descriptor) bool {
+	if l.Annotations[AnnotationUnpack] == "true" {
+		return true
+	}
+	for _, suffix := range []string{"tar+gzip", "tar.gzip", ".tar.gz"} {
+		if strings.HasSuffix(l.MediaType, suffix) {
+			return true
+		}
+	}
+	return false
+}
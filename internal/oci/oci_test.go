@@ -0,0 +1,353 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/afero"
+)
+
+// A fakeRegistry is a minimal, read-only stand-in for an OCI registry.
+type fakeRegistry struct {
+	manifests  map[string][]byte
+	mediaTypes map[string]string
+	blobs      map[string][]byte
+	auth       string
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.auth != "" {
+		if u, p, ok := req.BasicAuth(); !ok || u+":"+p != r.auth {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	switch {
+	case req.URL.Path == "/v2/":
+		w.WriteHeader(http.StatusOK)
+	case strings.HasPrefix(req.URL.Path, "/v2/modules/vpc/manifests/"):
+		ref := strings.TrimPrefix(req.URL.Path, "/v2/modules/vpc/manifests/")
+		m, ok := r.manifests[ref]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mt := ocispec.MediaTypeImageManifest
+		if t, ok := r.mediaTypes[ref]; ok {
+			mt = t
+		}
+		w.Header().Set("Content-Type", mt)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(m).String())
+		_, _ = w.Write(m)
+	case strings.HasPrefix(req.URL.Path, "/v2/modules/vpc/blobs/"):
+		b, ok := r.blobs[strings.TrimPrefix(req.URL.Path, "/v2/modules/vpc/blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type layer struct {
+	desc ocispec.Descriptor
+	data []byte
+}
+
+func (r *fakeRegistry) push(t *testing.T, tag string, layers ...layer) string {
+	t.Helper()
+	config := []byte("{}")
+	r.blobs[digest.FromBytes(config).String()] = config
+	m := ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config: ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeEmptyJSON,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		},
+	}
+	m.SchemaVersion = 2
+	for _, l := range layers {
+		d, b := l.desc, l.data
+		d.Digest = digest.FromBytes(b)
+		d.Size = int64(len(b))
+		r.blobs[d.Digest.String()] = b
+		m.Layers = append(m.Layers, d)
+	}
+	j, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := digest.FromBytes(j).String()
+	r.manifests[tag] = j
+	r.manifests[d] = j
+	return d
+}
+
+func (r *fakeRegistry) pushIndex(t *testing.T, tag string) {
+	t.Helper()
+	j, err := json.Marshal(ocispec.Index{MediaType: ocispec.MediaTypeImageIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.manifests[tag] = j
+	r.mediaTypes[tag] = ocispec.MediaTypeImageIndex
+}
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPull(t *testing.T) {
+	dir := "/tf/no-you-id"
+
+	type args struct {
+		ref func(host string) string
+		o   func(host string) []PullOption
+
+		// pulled is true if the artifact was already pulled.
+		pulled bool
+	}
+	type want struct {
+		files map[string]string
+		err   bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		reg    func(t *testing.T, r *fakeRegistry)
+		args   args
+		want   want
+	}{
+		"TitledLayers": {
+			reason: "Layers with a title annotation should be written to files of that name.",
+			reg: func(t *testing.T, r *fakeRegistry) {
+				r.push(t, "v1",
+					layer{desc: ocispec.Descriptor{MediaType: "application/vnd.oci.image.layer.v1.tar", Annotations: map[string]string{ocispec.AnnotationTitle: "main.tf"}}, data: []byte("main")},
+					layer{desc: ocispec.Descriptor{MediaType: "application/vnd.oci.image.layer.v1.tar", Annotations: map[string]string{ocispec.AnnotationTitle: "sub/outputs.tf"}}, data: []byte("outputs")},
+				)
+			},
+			args: args{
+				ref: func(host string) string { return "oci://" + host + "/modules/vpc:v1" },
+			},
+			want: want{
+				files: map[string]string{"main.tf": "main", "sub/outputs.tf": "outputs"},
+			},
+		},
+		"TarballLayer": {
+			reason: "Gzipped tarball layers should be extracted.",
+			reg: func(t *testing.T, r *fakeRegistry) {
+				r.push(t, "v1",
+					layer{desc: ocispec.Descriptor{MediaType: "application/vnd.cncf.flux.content.v1.tar+gzip"}, data: tarball(t, map[string]string{"main.tf": "main", "modules/a/main.tf": "a"})},
+				)
+			},
+			args: args{
+				ref: func(host string) string { return host + "/modules/vpc:v1" },
+			},
+			want: want{
+				files: map[string]string{"main.tf": "main", "modules/a/main.tf": "a"},
+			},
+		},
+		"TarballPathTraversal": {
+			reason: "Tarball entries outside of the module directory should be rejected.",
+			reg: func(t *testing.T, r *fakeRegistry) {
+				r.push(t, "v1",
+					layer{desc: ocispec.Descriptor{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip"}, data: tarball(t, map[string]string{"../escape.tf": "nope"})},
+				)
+			},
+			args: args{
+				ref: func(host string) string { return host + "/modules/vpc:v1" },
+			},
+			want: want{
+				err: true,
+			},
+		},
+		"Unchanged": {
+			reason: "An artifact that was already pulled should not be pulled again.",
+			reg: func(t *testing.T, r *fakeRegistry) {
+				r.push(t, "v1",
+					layer{desc: ocispec.Descriptor{MediaType: "text/plain", Annotations: map[string]string{ocispec.AnnotationTitle: "main.tf"}}, data: []byte("main")},
+				)
+			},
+			args: args{
+				ref:    func(host string) string { return host + "/modules/vpc:v1" },
+				pulled: true,
+			},
+			want: want{
+				files: map[string]string{},
+			},
+		},
+		"ImageIndex": {
+			reason: "References that resolve to an image index rather than a manifest should be rejected.",
+			reg: func(t *testing.T, r *fakeRegistry) {
+				r.pushIndex(t, "v1")
+			},
+			args: args{
+				ref: func(host string) string { return host + "/modules/vpc:v1" },
+			},
+			want: want{
+				err: true,
+			},
+		},
+		"NoLayers": {
+			reason: "Artifacts without layers that contain module files should be rejected, rather than pulled as an empty module.",
+			reg: func(t *testing.T, r *fakeRegistry) {
+				r.push(t, "v1",
+					layer{desc: ocispec.Descriptor{MediaType: "application/octet-stream"}, data: []byte("unknown")},
+				)
+			},
+			args: args{
+				ref: func(host string) string { return host + "/modules/vpc:v1" },
+			},
+			want: want{
+				err: true,
+			},
+		},
+		"Authenticated": {
+			reason: "Credentials from a Docker config should be used to authenticate to the registry.",
+			reg: func(t *testing.T, r *fakeRegistry) {
+				r.auth = "user:pass"
+				r.push(t, "v1",
+					layer{desc: ocispec.Descriptor{MediaType: "text/plain", Annotations: map[string]string{ocispec.AnnotationTitle: "main.tf"}}, data: []byte("main")},
+				)
+			},
+			args: args{
+				ref: func(host string) string { return host + "/modules/vpc:v1" },
+				o: func(host string) []PullOption {
+					return []PullOption{WithDockerConfigJSON([]byte(`{"auths":{"` + host + `":{"auth":"dXNlcjpwYXNz"}}}`))}
+				},
+			},
+			want: want{
+				files: map[string]string{"main.tf": "main"},
+			},
+		},
+		"Unauthenticated": {
+			reason: "Pulls should fail if the registry requires credentials that were not supplied.",
+			reg: func(t *testing.T, r *fakeRegistry) {
+				r.auth = "user:pass"
+				r.push(t, "v1")
+			},
+			args: args{
+				ref: func(host string) string { return host + "/modules/vpc:v1" },
+			},
+			want: want{
+				err: true,
+			},
+		},
+		"NotFound": {
+			reason: "Pulls should fail if the artifact does not exist.",
+			reg:    func(t *testing.T, r *fakeRegistry) {},
+			args: args{
+				ref: func(host string) string { return host + "/modules/vpc:v1" },
+			},
+			want: want{
+				err: true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := &fakeRegistry{manifests: map[string][]byte{}, mediaTypes: map[string]string{}, blobs: map[string][]byte{}}
+			tc.reg(t, r)
+			srv := httptest.NewTLSServer(r)
+			defer srv.Close()
+			host := strings.TrimPrefix(srv.URL, "https://")
+
+			o := []PullOption{WithHTTPClient(srv.Client())}
+			if tc.args.o != nil {
+				o = append(o, tc.args.o(host)...)
+			}
+			if tc.args.pulled {
+				o = append(o, WithPulledDigest(digest.FromBytes(r.manifests["v1"]).String()))
+			}
+
+			fs := afero.Afero{Fs: afero.NewMemMapFs()}
+			d, files, err := Pull(context.Background(), fs, tc.args.ref(host), dir, o...)
+			if tc.want.err != (err != nil) {
+				t.Fatalf("\n%s\nPull(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if d != digest.FromBytes(r.manifests["v1"]).String() {
+				t.Errorf("\n%s\nPull(...): want digest of manifest, got %s", tc.reason, d)
+			}
+			want := make([]string, 0, len(tc.want.files))
+			for name := range tc.want.files {
+				want = append(want, filepath.Clean(name))
+			}
+			sort.Strings(want)
+			sort.Strings(files)
+			if diff := cmp.Diff(want, files, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nPull(...): -want written files, +got written files:\n%s", tc.reason, diff)
+			}
+			if ok, _ := fs.DirExists(dir); tc.args.pulled && ok {
+				t.Errorf("\n%s\nPull(...): want nothing written, got directory %s", tc.reason, dir)
+			}
+
+			got := map[string]string{}
+			for name := range tc.want.files {
+				b, err := fs.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[name] = string(b)
+			}
+			if diff := cmp.Diff(tc.want.files, got); diff != "" {
+				t.Errorf("\n%s\nPull(...): -want files, +got files:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
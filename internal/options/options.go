@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package options configures the Workspace controllers.
+package options
+
+import (
+	"time"
+
+	"github.com/upbound/provider-terraform/internal/job"
+	"github.com/upbound/provider-terraform/internal/modcache"
+	"github.com/upbound/provider-terraform/internal/shard"
+	"github.com/upbound/provider-terraform/internal/workdir"
+)
+
+// Workspace configures the cluster scoped and namespaced Workspace
+// controllers.
+type Workspace struct {
+	// ModuleCache caches modules from Remote sources. Modules are not cached
+	// if it is nil.
+	ModuleCache *modcache.Cache
+
+	// ChecksumIgnore are patterns of paths within a Workspace's working
+	// directory that are excluded from its checksum, in addition to those
+	// that are always excluded. Changes to excluded paths don't cause
+	// Terraform to be initialized again.
+	ChecksumIgnore []string
+
+	// Metrics records the disk usage of working directories, and what the
+	// working directory garbage collectors did. No metrics are recorded if it
+	// is nil.
+	Metrics *workdir.Metrics
+
+	// MaxDiskUsage is the size in bytes that the working directories of a
+	// Workspace may grow to before it fails. There is no maximum if it is
+	// zero.
+	MaxDiskUsage int64
+
+	// DiskUsage periodically measures the disk usage of working directories.
+	// It is measured each time a Workspace is reconciled if it is nil.
+	DiskUsage *workdir.DiskUsage
+
+	// Shard determines which Workspaces are reconciled by this replica. All
+	// Workspaces are reconciled if it is nil.
+	Shard *shard.Sharder
+
+	// Jobs runs Terraform in Kubernetes Jobs for Workspaces that ask for it.
+	// Workspaces can't run Terraform in Jobs if it is nil.
+	Jobs *job.Launcher
+
+	// Timeouts are the default timeouts of Terraform operations. Workspaces
+	// may override them.
+	Timeouts Timeouts
+}
+
+// Timeouts bound how long Terraform operations may run. An operation whose
+// timeout is zero is bounded only by the timeout of the reconcile.
+type Timeouts struct {
+	Init    time.Duration
+	Plan    time.Duration
+	Apply   time.Duration
+	Destroy time.Duration
+}
+
+// Reconcile returns how long the Terraform operations of a single reconcile
+// may run for, i.e. an init, a plan, and an apply or destroy. Operations that
+// aren't bounded count as zero, so a reconcile may run for longer.
+func (t Timeouts) Reconcile() time.Duration {
+	return max(t.Init, 0) + max(t.Plan, 0) + max(t.Apply, t.Destroy, 0)
+}
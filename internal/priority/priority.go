@@ -0,0 +1,139 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package priority orders the work queue of a controller, so that Workspaces
+// with a higher priority are reconciled first when the controller has more
+// Workspaces to reconcile than it has workers.
+package priority
+
+import (
+	"container/heap"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// A Func returns the priority of the supplied request.
+type Func func(r reconcile.Request) int32
+
+// NewQueue returns a function that constructs a controller's work queue, for
+// use as controller.Options.NewQueue. Requests are dequeued in order of the
+// priority returned by the supplied function. Rate limiting, delays and
+// metrics are those of the controller's default work queue.
+func NewQueue(fn Func) func(name string, rl workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+	return func(name string, rl workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		q := workqueue.NewTypedWithConfig(workqueue.TypedQueueConfig[reconcile.Request]{
+			Name:  name,
+			Queue: NewStorage(fn),
+		})
+		d := workqueue.NewTypedDelayingQueueWithConfig(workqueue.TypedDelayingQueueConfig[reconcile.Request]{
+			Name:  name,
+			Queue: q,
+		})
+		return workqueue.NewTypedRateLimitingQueueWithConfig(rl, workqueue.TypedRateLimitingQueueConfig[reconcile.Request]{
+			DelayingQueue: d,
+		})
+	}
+}
+
+// Storage stores the requests of a work queue, and pops the request with the
+// highest priority first. Requests of equal priority are popped in the order
+// they were pushed.
+type Storage struct {
+	priority Func
+	entries  entries
+	index    map[reconcile.Request]*entry
+	seq      uint64
+}
+
+// NewStorage returns work queue storage that orders requests by the priority
+// returned by the supplied function.
+func NewStorage(fn Func) *Storage {
+	return &Storage{priority: fn, index: map[reconcile.Request]*entry{}}
+}
+
+// Touch is called when a request that is already queued is added again. Its
+// priority may have changed since it was queued.
+func (s *Storage) Touch(r reconcile.Request) {
+	e, ok := s.index[r]
+	if !ok {
+		return
+	}
+	if p := s.priority(r); p != e.priority {
+		e.priority = p
+		heap.Fix(&s.entries, e.i)
+	}
+}
+
+// Push a request.
+func (s *Storage) Push(r reconcile.Request) {
+	e := &entry{request: r, priority: s.priority(r), seq: s.seq}
+	s.seq++
+	s.index[r] = e
+	heap.Push(&s.entries, e)
+}
+
+// Len returns the number of stored requests.
+func (s *Storage) Len() int {
+	return len(s.entries)
+}
+
+// Pop the request with the highest priority.
+func (s *Storage) Pop() reconcile.Request {
+	e := heap.Pop(&s.entries).(*entry) //nolint:forcetypeassert // Only entries are pushed.
+	delete(s.index, e.request)
+	return e.request
+}
+
+type entry struct {
+	request  reconcile.Request
+	priority int32
+	seq      uint64
+	i        int
+}
+
+// entries implements heap.Interface.
+type entries []*entry
+
+func (e entries) Len() int { return len(e) }
+
+func (e entries) Less(i, j int) bool {
+	if e[i].priority != e[j].priority {
+		return e[i].priority > e[j].priority
+	}
+	return e[i].seq < e[j].seq
+}
+
+func (e entries) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+	e[i].i = i
+	e[j].i = j
+}
+
+func (e *entries) Push(x any) {
+	en := x.(*entry) //nolint:forcetypeassert // Only entries are pushed.
+	en.i = len(*e)
+	*e = append(*e, en)
+}
+
+func (e *entries) Pop() any {
+	old := *e
+	n := len(old)
+	en := old[n-1]
+	old[n-1] = nil
+	*e = old[:n-1]
+	return en
+}
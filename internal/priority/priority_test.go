@@ -0,0 +1,104 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func req(name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+}
+
+func TestStorage(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		priority map[string]int32
+		push     []string
+		touch    map[string]int32
+		want     []string
+	}{
+		"EqualPriority": {
+			reason: "Requests of equal priority should be popped in the order they were pushed.",
+			push:   []string{"a", "b", "c"},
+			want:   []string{"a", "b", "c"},
+		},
+		"HigherPriorityFirst": {
+			reason:   "Requests of higher priority should be popped first.",
+			priority: map[string]int32{"critical": 100, "bulk": -10},
+			push:     []string{"bulk", "a", "critical", "b"},
+			want:     []string{"critical", "a", "b", "bulk"},
+		},
+		"PriorityChanged": {
+			reason:   "A request whose priority changes while it's queued should be reordered when it's added again.",
+			priority: map[string]int32{"a": 1},
+			push:     []string{"a", "b", "c"},
+			touch:    map[string]int32{"c": 10},
+			want:     []string{"c", "a", "b"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := map[string]int32{}
+			for k, v := range tc.priority {
+				p[k] = v
+			}
+			s := NewStorage(func(r reconcile.Request) int32 { return p[r.Name] })
+			for _, n := range tc.push {
+				s.Push(req(n))
+			}
+			for n, v := range tc.touch {
+				p[n] = v
+				s.Touch(req(n))
+			}
+
+			got := make([]string, 0, s.Len())
+			for s.Len() > 0 {
+				got = append(got, s.Pop().Name)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ns.Pop(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewQueue(t *testing.T) {
+	p := map[string]int32{"critical": 1}
+	q := NewQueue(func(r reconcile.Request) int32 { return p[r.Name] })("test", workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer q.ShutDown()
+
+	q.Add(req("bulk"))
+	q.Add(req("critical"))
+	q.Add(req("bulk")) // Already queued.
+
+	got := make([]string, 0, q.Len())
+	for q.Len() > 0 {
+		r, _ := q.Get()
+		got = append(got, r.Name)
+		q.Done(r)
+	}
+	if diff := cmp.Diff([]string{"critical", "bulk"}, got); diff != "" {
+		t.Errorf("q.Get(...): -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package revision resolves the revisions of remote Terraform module sources
+// without fetching them.
+package revision
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	getter "github.com/hashicorp/go-getter"
+	"github.com/pkg/errors"
+)
+
+// Error strings.
+const (
+	errDetect     = "cannot detect module source"
+	errParse      = "cannot parse module source"
+	errLsRemote   = "cannot list remote git references"
+	errFmtNoMatch = "no remote git reference matches %q"
+)
+
+var commit = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// An LsRemoteFn returns the output of git ls-remote for the supplied
+// repository and ref. Git is run with the supplied additional environment
+// variables.
+type LsRemoteFn func(ctx context.Context, repo, ref string, env ...string) ([]byte, error)
+
+// LsRemote runs git ls-remote.
+func LsRemote(ctx context.Context, repo, ref string, env ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--", repo, ref) //nolint:gosec // We want to run git with these arguments.
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Output()
+}
+
+// A Resolver resolves the revisions of remote module sources.
+type Resolver struct {
+	lsRemote LsRemoteFn
+}
+
+// An Option configures a Resolver.
+type Option func(r *Resolver)
+
+// WithLsRemote configures how a Resolver lists remote git references. The
+// default is to run git ls-remote.
+func WithLsRemote(fn LsRemoteFn) Option {
+	return func(r *Resolver) { r.lsRemote = fn }
+}
+
+// NewResolver returns a Resolver of remote module sources.
+func NewResolver(o ...Option) *Resolver {
+	r := &Resolver{lsRemote: LsRemote}
+	for _, fn := range o {
+		fn(r)
+	}
+	return r
+}
+
+// Resolve the supplied module source, per go-getter, to a git commit. An
+// empty revision is returned if the source is not a git repository, or if
+// its revision can't be resolved without fetching it. Sources whose ref is a
+// commit are resolved without accessing the repository. Git is run with the
+// supplied additional environment variables.
+func (r *Resolver) Resolve(ctx context.Context, src, pwd string, env ...string) (string, error) {
+	u, err := getter.Detect(src, pwd, getter.Detectors)
+	if err != nil {
+		return "", errors.Wrap(err, errDetect)
+	}
+	u, ok := strings.CutPrefix(u, "git::")
+	if !ok {
+		return "", nil
+	}
+	u, _ = getter.SourceDirSubdir(u)
+
+	repo, err := url.Parse(u)
+	if err != nil {
+		return "", errors.Wrap(err, errParse)
+	}
+	q := repo.Query()
+	if q.Has("sshkey") {
+		// We'd need to write the key to disk to use it.
+		return "", nil
+	}
+	ref := q.Get("ref")
+	if commit.MatchString(ref) {
+		return ref, nil
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	q.Del("ref")
+	q.Del("depth")
+	repo.RawQuery = q.Encode()
+
+	out, err := r.lsRemote(ctx, repo.String(), ref, env...)
+	if err != nil {
+		return "", errors.Wrap(err, errLsRemote)
+	}
+	// Each line is a commit and a reference, separated by a tab. Git matches
+	// the ref against the end of each reference, so it may list references
+	// other than the one go-getter would check out, e.g. refs/heads/team/main
+	// for main.
+	refs := map[string]string{}
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if c, name, ok := strings.Cut(s.Text(), "\t"); ok && commit.MatchString(c) {
+			refs[name] = c
+		}
+	}
+	for _, name := range candidates(ref) {
+		// Annotated tags are listed twice: once as the tag object, and once
+		// peeled to the commit it tags.
+		if c, ok := refs[name+"^{}"]; ok {
+			return c, nil
+		}
+		if c, ok := refs[name]; ok {
+			return c, nil
+		}
+	}
+	return "", errors.Errorf(errFmtNoMatch, ref)
+}
+
+// candidates returns the references the supplied ref may refer to, in order
+// of precedence.
+func candidates(ref string) []string {
+	if ref == "HEAD" || strings.HasPrefix(ref, "refs/") {
+		return []string{ref}
+	}
+	return []string{"refs/heads/" + ref, "refs/tags/" + ref}
+}
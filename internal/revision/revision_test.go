@@ -0,0 +1,161 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+)
+
+const (
+	sha  = "0123456789abcdef0123456789abcdef01234567"
+	sha2 = "89abcdef0123456789abcdef0123456789abcdef"
+)
+
+func TestResolve(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type lsRemote struct {
+		repo string
+		ref  string
+	}
+	type want struct {
+		rev      string
+		lsRemote *lsRemote
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		src    string
+		out    []byte
+		err    error
+		want   want
+	}{
+		"NotGit": {
+			reason: "Sources that are not git repositories should not be resolved.",
+			src:    "https://example.org/module.zip",
+			want:   want{},
+		},
+		"Commit": {
+			reason: "Refs that are commits should be resolved without accessing the repository.",
+			src:    "git::https://example.org/module.git?ref=" + sha,
+			want:   want{rev: sha},
+		},
+		"Tag": {
+			reason: "Refs should be resolved by listing the repository's references.",
+			src:    "git::https://example.org/modules.git//vpc?ref=v1.0.0&depth=1",
+			out:    []byte(sha + "\trefs/tags/v1.0.0\n"),
+			want: want{
+				rev:      sha,
+				lsRemote: &lsRemote{repo: "https://example.org/modules.git", ref: "v1.0.0"},
+			},
+		},
+		"AnnotatedTag": {
+			reason: "Annotated tags should be resolved to the commit they tag, not to the tag object.",
+			src:    "git::https://example.org/modules.git//vpc?ref=v1.0.0&depth=1",
+			out:    []byte(sha + "\trefs/tags/v1.0.0\n" + sha2 + "\trefs/tags/v1.0.0^{}\n"),
+			want: want{
+				rev:      sha2,
+				lsRemote: &lsRemote{repo: "https://example.org/modules.git", ref: "v1.0.0"},
+			},
+		},
+		"Detected": {
+			reason: "Sources should be detected per go-getter before they are resolved.",
+			src:    "github.com/example/modules//vpc",
+			out:    []byte(sha + "\tHEAD\n"),
+			want: want{
+				rev:      sha,
+				lsRemote: &lsRemote{repo: "https://github.com/example/modules.git", ref: "HEAD"},
+			},
+		},
+		"SSH": {
+			reason: "SCP-like SSH sources should be resolved.",
+			src:    "git@github.com:example/modules.git?ref=main",
+			out:    []byte(sha + "\trefs/heads/main\n"),
+			want: want{
+				rev:      sha,
+				lsRemote: &lsRemote{repo: "ssh://git@github.com/example/modules.git", ref: "main"},
+			},
+		},
+		"SimilarRefs": {
+			reason: "Refs should only match references of exactly that name, preferring branches to tags.",
+			src:    "git::https://example.org/module.git?ref=main",
+			out:    []byte(sha + "\trefs/heads/team/main\n" + sha2 + "\trefs/heads/main\n" + sha + "\trefs/tags/main\n"),
+			want: want{
+				rev:      sha2,
+				lsRemote: &lsRemote{repo: "https://example.org/module.git", ref: "main"},
+			},
+		},
+		"OnlySimilarRefs": {
+			reason: "An error should be returned if only references that end with the ref match it.",
+			src:    "git::https://example.org/module.git?ref=main",
+			out:    []byte(sha + "\trefs/heads/team/main\n"),
+			want: want{
+				lsRemote: &lsRemote{repo: "https://example.org/module.git", ref: "main"},
+				err:      errors.Errorf(errFmtNoMatch, "main"),
+			},
+		},
+		"SSHKey": {
+			reason: "Sources that use an SSH key should not be resolved.",
+			src:    "git::ssh://git@example.org/module.git?sshkey=c2VjcmV0",
+			want:   want{},
+		},
+		"LsRemoteError": {
+			reason: "Errors listing the repository's references should be returned.",
+			src:    "git::https://example.org/module.git",
+			err:    errBoom,
+			want: want{
+				lsRemote: &lsRemote{repo: "https://example.org/module.git", ref: "HEAD"},
+				err:      errors.Wrap(errBoom, errLsRemote),
+			},
+		},
+		"NoMatch": {
+			reason: "An error should be returned if no reference matches the ref.",
+			src:    "git::https://example.org/module.git?ref=nope",
+			want: want{
+				lsRemote: &lsRemote{repo: "https://example.org/module.git", ref: "nope"},
+				err:      errors.Errorf(errFmtNoMatch, "nope"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got *lsRemote
+			r := NewResolver(WithLsRemote(func(_ context.Context, repo, ref string, _ ...string) ([]byte, error) {
+				got = &lsRemote{repo: repo, ref: ref}
+				return tc.out, tc.err
+			}))
+			rev, err := r.Resolve(context.Background(), tc.src, "/tf/ws")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nr.Resolve(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.rev, rev); diff != "" {
+				t.Errorf("\n%s\nr.Resolve(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.lsRemote, got, cmp.AllowUnexported(lsRemote{})); diff != "" {
+				t.Errorf("\n%s\nr.Resolve(...): -want ls-remote, +got ls-remote:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
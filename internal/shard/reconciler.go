@@ -0,0 +1,84 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shard
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const errGetWorkspace = "cannot get workspace"
+
+// A Reconciler only reconciles objects owned by this replica.
+type Reconciler struct {
+	inner  reconcile.Reconciler
+	kube   client.Reader
+	owner  *Sharder
+	newObj func() client.Object
+}
+
+// NewReconciler returns a Reconciler that passes requests for objects owned
+// by this replica to the supplied Reconciler, and ignores all others. Objects
+// are read from the supplied client using the supplied function.
+func NewReconciler(r reconcile.Reconciler, c client.Reader, s *Sharder, newObj func() client.Object) *Reconciler {
+	return &Reconciler{inner: r, kube: c, owner: s, newObj: newObj}
+}
+
+// Reconcile the supplied request, if this replica owns its object. Requests
+// for objects that don't exist are always passed on, so that they're handled
+// as they would be without sharding.
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	o := r.newObj()
+	if err := r.kube.Get(ctx, req.NamespacedName, o); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, errors.Wrap(err, errGetWorkspace)
+		}
+		return r.inner.Reconcile(ctx, req)
+	}
+	if !r.owner.Owns(o) {
+		// The owner will reconcile it. If ownership moves to this replica
+		// it will be enqueued again.
+		return reconcile.Result{}, nil
+	}
+	return r.inner.Reconcile(ctx, req)
+}
+
+// Source returns a source that enqueues every object listed by the supplied
+// function whenever the members of the shard group change.
+func (s *Sharder) Source(list func(ctx context.Context) ([]client.Object, error)) source.Source {
+	return source.Channel(s.Subscribe(), handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, _ client.Object) []reconcile.Request {
+		objs, err := list(ctx)
+		if err != nil {
+			s.log.Info("Cannot list objects to rebalance", "error", err)
+			return nil
+		}
+		reqs := make([]reconcile.Request, 0, len(objs))
+		for _, o := range objs {
+			if !s.Owns(o) {
+				continue
+			}
+			reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}})
+		}
+		return reqs
+	}))
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package shard spreads Workspaces across provider replicas. Each replica
+// holds a Lease while it is running. The replicas that hold unexpired Leases
+// are the members of the shard group, and each Workspace is owned by exactly
+// one member, chosen by rendezvous hashing. When members join or leave the
+// group only the Workspaces owned by those members move to other replicas.
+package shard
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+)
+
+// Error strings.
+const (
+	errGetLease    = "cannot get shard lease"
+	errCreateLease = "cannot create shard lease"
+	errUpdateLease = "cannot update shard lease"
+	errListLeases  = "cannot list shard leases"
+)
+
+// LabelGroup identifies the shard group a Lease belongs to.
+const LabelGroup = "tf.upbound.io/shard-group"
+
+// A Sharder determines which Workspaces are owned by this replica.
+type Sharder struct {
+	kube      client.Client
+	namespace string
+	group     string
+	identity  string
+	keyLabel  string
+	duration  time.Duration
+	renew     time.Duration
+	log       logging.Logger
+	lastRenew time.Time
+
+	mu          sync.RWMutex
+	members     []string
+	synced      bool
+	subscribers []chan event.GenericEvent
+}
+
+// An Option configures a new Sharder.
+type Option func(s *Sharder)
+
+// WithGroup configures the name of the shard group. Replicas only share
+// Workspaces with other replicas in the same group. The default group is
+// provider-terraform.
+func WithGroup(g string) Option {
+	return func(s *Sharder) { s.group = g }
+}
+
+// WithKeyLabel configures a label whose value is hashed to choose the owner of
+// a Workspace, rather than its UID. Workspaces with the same label value are
+// owned by the same replica. Workspaces without the label are hashed by UID.
+func WithKeyLabel(l string) Option {
+	return func(s *Sharder) { s.keyLabel = l }
+}
+
+// WithLeaseDuration configures how long a replica remains a member of the
+// shard group after it last renewed its Lease. Leases are renewed at a third
+// of this interval. The default is 30 seconds.
+func WithLeaseDuration(d time.Duration) Option {
+	return func(s *Sharder) {
+		s.duration = d
+		s.renew = d / 3
+	}
+}
+
+// WithLogger configures the logger that will be used. The default is a no-op
+// logger that never emits logs.
+func WithLogger(l logging.Logger) Option {
+	return func(s *Sharder) { s.log = l }
+}
+
+// New returns a Sharder that maintains a Lease with the supplied identity in
+// the supplied namespace. The supplied client should not be backed by a cache.
+func New(c client.Client, namespace, identity string, o ...Option) *Sharder {
+	s := &Sharder{
+		kube:      c,
+		namespace: namespace,
+		group:     "provider-terraform",
+		identity:  identity,
+		duration:  30 * time.Second,
+		renew:     10 * time.Second,
+		log:       logging.NewNopLogger(),
+	}
+	for _, fn := range o {
+		fn(s)
+	}
+	return s
+}
+
+// NeedLeaderElection returns false, because every replica must maintain its
+// membership of the shard group.
+func (s *Sharder) NeedLeaderElection() bool {
+	return false
+}
+
+// Start maintains this replica's membership of the shard group until the
+// supplied context is done, at which point this replica leaves the group.
+func (s *Sharder) Start(ctx context.Context) error {
+	t := time.NewTicker(s.renew)
+	defer t.Stop()
+	for {
+		if err := s.sync(ctx); err != nil {
+			s.log.Info("Cannot sync shard group membership", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			s.leave()
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Synced returns true once the members of the shard group are known.
+func (s *Sharder) Synced() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.synced
+}
+
+// Owns returns true if the supplied Workspace is owned by this replica.
+func (s *Sharder) Owns(o metav1.Object) bool {
+	key := string(o.GetUID())
+	if v, ok := o.GetLabels()[s.keyLabel]; s.keyLabel != "" && ok {
+		key = v
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Owner(s.members, key) == s.identity
+}
+
+// MayOwn returns true if the supplied Workspace is owned by this replica, or
+// if the members of the shard group aren't known yet. It's used to avoid
+// removing state, such as working directories, that this replica may still
+// need.
+func (s *Sharder) MayOwn(o metav1.Object) bool {
+	return !s.Synced() || s.Owns(o)
+}
+
+// Subscribe returns a channel that receives an event whenever the members of
+// the shard group change, and thus the Workspaces owned by this replica may
+// have changed.
+func (s *Sharder) Subscribe() <-chan event.GenericEvent {
+	ch := make(chan event.GenericEvent, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, ch)
+	if s.synced {
+		// The subscriber missed the last change.
+		ch <- s.event()
+	}
+	return ch
+}
+
+// Owner returns the member that owns the supplied key, using rendezvous
+// hashing. It returns an empty string if there are no members.
+func Owner(members []string, key string) string {
+	owner, best := "", uint64(0)
+	for _, m := range members {
+		if w := weight(m, key); owner == "" || w > best || (w == best && m < owner) {
+			owner, best = m, w
+		}
+	}
+	return owner
+}
+
+func weight(member, key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(member))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	// FNV doesn't mix its last bytes well, so finalize it per splitmix64.
+	x := binary.BigEndian.Uint64(h.Sum(nil))
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// sync renews this replica's Lease, then updates the members of the shard
+// group from the Leases that haven't expired.
+func (s *Sharder) sync(ctx context.Context) error {
+	if err := s.renewLease(ctx); err != nil {
+		// Once our Lease expires other replicas take over our Workspaces,
+		// so we must stop reconciling them.
+		if time.Since(s.lastRenew) > s.duration {
+			s.setMembers(nil)
+		}
+		return err
+	}
+	s.lastRenew = time.Now()
+
+	l := &coordinationv1.LeaseList{}
+	if err := s.kube.List(ctx, l, client.InNamespace(s.namespace), client.MatchingLabels{LabelGroup: s.group}); err != nil {
+		return errors.Wrap(err, errListLeases)
+	}
+	members := []string{s.identity}
+	now := time.Now()
+	for _, lease := range l.Items {
+		id := ptr.Deref(lease.Spec.HolderIdentity, "")
+		if id == "" || id == s.identity || lease.Spec.RenewTime == nil {
+			continue
+		}
+		d := time.Duration(ptr.Deref(lease.Spec.LeaseDurationSeconds, 0)) * time.Second
+		if lease.Spec.RenewTime.Add(d).Before(now) {
+			continue
+		}
+		members = append(members, id)
+	}
+	sort.Strings(members)
+	s.setMembers(members)
+	return nil
+}
+
+func (s *Sharder) setMembers(members []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.synced && slices.Equal(s.members, members) {
+		return
+	}
+	s.log.Info("Shard group membership changed", "group", s.group, "members", members)
+	s.members = members
+	s.synced = true
+	for _, ch := range s.subscribers {
+		// A pending event already causes every Workspace to be reconsidered.
+		select {
+		case ch <- s.event():
+		default:
+		}
+	}
+}
+
+func (s *Sharder) event() event.GenericEvent {
+	return event.GenericEvent{Object: &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: s.leaseName()}}}
+}
+
+func (s *Sharder) leaseName() string {
+	return s.group + "-" + s.identity
+}
+
+func (s *Sharder) renewLease(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{}
+	err := s.kube.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.leaseName()}, lease)
+	if kerrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.namespace,
+				Name:      s.leaseName(),
+				Labels:    map[string]string{LabelGroup: s.group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(s.identity),
+				LeaseDurationSeconds: ptr.To(int32(s.duration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		return errors.Wrap(s.kube.Create(ctx, lease), errCreateLease)
+	}
+	if err != nil {
+		return errors.Wrap(err, errGetLease)
+	}
+	lease.Spec.HolderIdentity = ptr.To(s.identity)
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(s.duration.Seconds()))
+	lease.Spec.RenewTime = &now
+	return errors.Wrap(s.kube.Update(ctx, lease), errUpdateLease)
+}
+
+// leave the shard group by deleting this replica's Lease, so that its
+// Workspaces move to other replicas without waiting for it to expire.
+func (s *Sharder) leave() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: s.leaseName()}}
+	if err := s.kube.Delete(ctx, lease); client.IgnoreNotFound(err) != nil {
+		s.log.Info("Cannot delete shard lease", "error", err)
+	}
+}
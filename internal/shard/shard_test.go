@@ -0,0 +1,215 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+)
+
+func TestOwner(t *testing.T) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	three := []string{"a", "b", "c"}
+	four := []string{"a", "b", "c", "d"}
+
+	owned := map[string]int{}
+	for _, k := range keys {
+		before, after := Owner(three, k), Owner(four, k)
+		owned[before]++
+
+		// Adding a member must only move keys to that member.
+		if before != after && after != "d" {
+			t.Errorf("Owner(...): key %q moved from %q to %q, want it to stay or move to %q", k, before, after, "d")
+		}
+	}
+
+	// Each member should own roughly a third of the keys.
+	for _, m := range three {
+		if owned[m] < 250 || owned[m] > 420 {
+			t.Errorf("Owner(...): member %q owns %d of %d keys, want roughly a third", m, owned[m], len(keys))
+		}
+	}
+
+	if diff := cmp.Diff("", Owner(nil, "key")); diff != "" {
+		t.Errorf("Owner(nil, ...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestSync(t *testing.T) {
+	errBoom := errors.New("boom")
+	now := metav1.NewMicroTime(time.Now())
+	expired := metav1.NewMicroTime(time.Now().Add(-time.Hour))
+
+	lease := func(id string, renewed metav1.MicroTime) coordinationv1.Lease {
+		return coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To(id),
+			LeaseDurationSeconds: ptr.To(int32(30)),
+			RenewTime:            &renewed,
+		}}
+	}
+
+	type want struct {
+		members []string
+		created bool
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		kube   *test.MockClient
+		want   want
+	}{
+		"CreateLease": {
+			reason: "We should create our Lease if it doesn't exist, and include ourselves in the members.",
+			kube: &test.MockClient{
+				MockGet:    test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+				MockCreate: test.NewMockCreateFn(nil),
+				MockList:   test.NewMockListFn(nil),
+			},
+			want: want{members: []string{"me"}, created: true},
+		},
+		"UnexpiredLeases": {
+			reason: "Replicas holding unexpired Leases should be members.",
+			kube: &test.MockClient{
+				MockGet:    test.NewMockGetFn(nil),
+				MockUpdate: test.NewMockUpdateFn(nil),
+				MockList: test.NewMockListFn(nil, func(obj client.ObjectList) error {
+					obj.(*coordinationv1.LeaseList).Items = []coordinationv1.Lease{
+						lease("me", now),
+						lease("other", now),
+						lease("gone", expired),
+					}
+					return nil
+				}),
+			},
+			want: want{members: []string{"me", "other"}},
+		},
+		"RenewError": {
+			reason: "We should return an error if we can't renew our Lease.",
+			kube: &test.MockClient{
+				MockGet:    test.NewMockGetFn(nil),
+				MockUpdate: test.NewMockUpdateFn(errBoom),
+			},
+			want: want{err: errors.Wrap(errBoom, errUpdateLease)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			created := false
+			if tc.kube.MockCreate != nil {
+				mc := tc.kube.MockCreate
+				tc.kube.MockCreate = func(ctx context.Context, obj client.Object, o ...client.CreateOption) error {
+					created = true
+					return mc(ctx, obj, o...)
+				}
+			}
+			s := New(tc.kube, "ns", "me")
+			s.lastRenew = time.Now()
+			err := s.sync(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ns.sync(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.members, s.members); diff != "" {
+				t.Errorf("\n%s\ns.sync(...): -want members, +got members:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.created, created); diff != "" {
+				t.Errorf("\n%s\ns.sync(...): -want created, +got created:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	// Find keys owned by each of two members.
+	var mine, theirs string
+	for i := 0; mine == "" || theirs == ""; i++ {
+		k := fmt.Sprintf("uid-%d", i)
+		if Owner([]string{"me", "other"}, k) == "me" {
+			mine = k
+		} else {
+			theirs = k
+		}
+	}
+
+	cases := map[string]struct {
+		reason string
+		uid    string
+		get    error
+		want   bool
+	}{
+		"Owned": {
+			reason: "We should reconcile objects we own.",
+			uid:    mine,
+			want:   true,
+		},
+		"NotOwned": {
+			reason: "We should not reconcile objects owned by another replica.",
+			uid:    theirs,
+			want:   false,
+		},
+		"NotFound": {
+			reason: "We should pass on requests for objects that don't exist.",
+			get:    kerrors.NewNotFound(schema.GroupResource{}, ""),
+			want:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := New(nil, "ns", "me")
+			s.setMembers([]string{"me", "other"})
+
+			kube := &test.MockClient{MockGet: test.NewMockGetFn(tc.get, func(obj client.Object) error {
+				obj.SetUID(types.UID(tc.uid))
+				return nil
+			})}
+			called := false
+			inner := reconcile.Func(func(_ context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				called = true
+				return reconcile.Result{}, nil
+			})
+
+			r := NewReconciler(inner, kube, s, func() client.Object { return &coordinationv1.Lease{} })
+			if _, err := r.Reconcile(context.Background(), reconcile.Request{}); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, called); diff != "" {
+				t.Errorf("\n%s\nr.Reconcile(...): -want called, +got called:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
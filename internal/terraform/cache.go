@@ -0,0 +1,295 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// installMu serializes inits that install the same provider into the shared
+// plugin cache, so that they don't write the same files at once. Terraform
+// only writes a provider version into the cache if it isn't already there, so
+// inits never replace a provider that is in use, which causes `text file busy`
+// errors, and Terraform commands that run providers don't need to lock the
+// cache.
+var installMu = &keyedMutex{}
+
+// A keyedMutex is a set of mutexes, identified by key.
+type keyedMutex struct {
+	mu sync.Mutex
+	m  map[string]*sync.Mutex
+}
+
+// Lock the mutexes identified by the supplied keys, returning a function that
+// unlocks them. Mutexes are always locked in the same order to avoid
+// deadlocks.
+func (k *keyedMutex) Lock(keys ...string) func() {
+	keys = append([]string{}, keys...)
+	sort.Strings(keys)
+
+	k.mu.Lock()
+	if k.m == nil {
+		k.m = map[string]*sync.Mutex{}
+	}
+	locks := make([]*sync.Mutex, 0, len(keys))
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		l, ok := k.m[key]
+		if !ok {
+			l = &sync.Mutex{}
+			k.m[key] = l
+		}
+		locks = append(locks, l)
+	}
+	k.mu.Unlock()
+
+	for _, l := range locks {
+		l.Lock()
+	}
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Unlock()
+		}
+	}
+}
+
+// cacheInstalls returns the providers that terraform init may install into
+// the shared plugin cache, identified by address. Providers whose locked
+// version is already in the cache aren't installed, unless providers are being
+// upgraded. It returns false if they can't be determined, in which case init
+// must not use the shared plugin cache. This is the case if the configuration
+// is copied from a module, or if it calls modules that aren't installed yet,
+// whose providers aren't known until init installs them.
+func (h Harness) cacheInstalls(args []string, s initState, serr error) ([]string, bool) {
+	if serr != nil {
+		return nil, false
+	}
+	upgrade := false
+	for _, a := range args {
+		if strings.HasPrefix(a, "-from-module") {
+			return nil, false
+		}
+		upgrade = upgrade || strings.HasPrefix(a, "-upgrade")
+	}
+	// Installed modules are only those init would install if the module
+	// calls didn't change since the last init.
+	last, ok := h.lastInitState()
+	_, err := os.Stat(filepath.Join(h.Dir, ".terraform", "modules", "modules.json"))
+	required, ok := h.requiredProviders(ok && err == nil && last.Modules == s.Modules)
+	if !ok {
+		return nil, false
+	}
+	locked := h.lockedProviders()
+	for addr := range locked {
+		required[addr] = true
+	}
+
+	cache := h.pluginCacheDir()
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	install := make([]string, 0, len(required))
+	for addr := range required {
+		if v, ok := locked[addr]; ok && cache != "" && !upgrade {
+			if _, err := os.Stat(filepath.Join(cache, addr, v, platform)); err == nil {
+				continue
+			}
+		}
+		install = append(install, addr)
+	}
+	sort.Strings(install)
+	return install, true
+}
+
+// requiredProviders returns the addresses of the providers required by the
+// configuration in the harness's directory, and by the modules it calls. The
+// modules installed by the last init are only considered if the supplied
+// argument is true. It returns false if the configuration calls modules that
+// aren't installed.
+func (h Harness) requiredProviders(modulesInstalled bool) (map[string]bool, bool) {
+	dotTerraform := filepath.Join(h.Dir, ".terraform")
+	installed := filepath.Join(dotTerraform, "modules")
+
+	// Local names and sources of providers, by module directory.
+	names := map[string]map[string]bool{}
+	sources := map[string]map[string]string{}
+	remote := false
+	err := filepath.WalkDir(h.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case !d.IsDir():
+		case d.Name() == ".git":
+			return filepath.SkipDir
+		case p == dotTerraform:
+			if !modulesInstalled {
+				return filepath.SkipDir
+			}
+		case filepath.Dir(p) == dotTerraform && p != installed:
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".tf") {
+			return nil
+		}
+		src, err := os.ReadFile(p) //nolint:gosec // We want to read configuration files.
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(p)
+		if names[dir] == nil {
+			names[dir], sources[dir] = map[string]bool{}, map[string]string{}
+		}
+		r, err := moduleProviders(p, src, names[dir], sources[dir])
+		remote = remote || r
+		return err
+	})
+	if err != nil || (remote && !modulesInstalled) {
+		return nil, false
+	}
+
+	addrs := map[string]bool{}
+	for dir, n := range names {
+		for name := range n {
+			src, ok := sources[dir][name]
+			if !ok && name == "terraform" {
+				// The built-in terraform provider is never installed.
+				continue
+			}
+			if !ok {
+				src = name
+			}
+			addrs[providerAddr(src)] = true
+		}
+	}
+	return addrs, true
+}
+
+// moduleProviders adds the local names of the providers used by the supplied
+// configuration file to the supplied names, and the sources of the providers
+// it requires to the supplied sources. It returns true if the file calls any
+// modules that aren't in the local filesystem.
+func moduleProviders(filename string, src []byte, names map[string]bool, sources map[string]string) (bool, error) {
+	f, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return false, diags
+	}
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return false, errors.New(errNativeSyntax)
+	}
+	remote := false
+	for _, b := range body.Blocks {
+		switch b.Type {
+		case "terraform":
+			for _, rp := range b.Body.Blocks {
+				if rp.Type != "required_providers" {
+					continue
+				}
+				for name, a := range rp.Body.Attributes {
+					names[name] = true
+					// Requirements are either an object with a source, or a
+					// version constraint for a provider whose source is
+					// implied by its local name.
+					v, diags := a.Expr.Value(nil)
+					if diags.HasErrors() || !v.Type().IsObjectType() || !v.Type().HasAttribute("source") {
+						continue
+					}
+					if s := v.GetAttr("source"); s.Type() == cty.String && s.IsKnown() && !s.IsNull() {
+						sources[name] = s.AsString()
+					}
+				}
+			}
+		case "provider":
+			if len(b.Labels) > 0 {
+				names[b.Labels[0]] = true
+			}
+		case "resource", "data", "ephemeral":
+			if len(b.Labels) > 0 {
+				names[strings.SplitN(b.Labels[0], "_", 2)[0]] = true
+			}
+		case "module":
+			a, ok := b.Body.Attributes["source"]
+			if !ok {
+				continue
+			}
+			v, diags := a.Expr.Value(nil)
+			if diags.HasErrors() || v.Type() != cty.String || !v.IsKnown() || v.IsNull() {
+				remote = true
+				continue
+			}
+			if s := v.AsString(); !strings.HasPrefix(s, "./") && !strings.HasPrefix(s, "../") {
+				remote = true
+			}
+		}
+	}
+	return remote, nil
+}
+
+// providerAddr returns the fully qualified address of the provider with the
+// supplied source address, e.g. registry.terraform.io/hashicorp/aws for aws.
+func providerAddr(src string) string {
+	parts := strings.Split(strings.ToLower(src), "/")
+	switch len(parts) {
+	case 1:
+		return "registry.terraform.io/hashicorp/" + parts[0]
+	case 2:
+		return "registry.terraform.io/" + parts[0] + "/" + parts[1]
+	default:
+		return strings.Join(parts, "/")
+	}
+}
+
+// lockedProviders returns the locked version of each provider in the
+// dependency lock file, by address.
+func (h Harness) lockedProviders() map[string]string {
+	p := map[string]string{}
+	b, err := os.ReadFile(filepath.Join(h.Dir, ".terraform.lock.hcl"))
+	if err != nil {
+		return p
+	}
+	for _, m := range lockedProvider.FindAllSubmatch(b, -1) {
+		p[strings.ToLower(string(m[1]))] = string(m[2])
+	}
+	return p
+}
+
+// pluginCacheDir returns the plugin cache directory used by terraform, if
+// any.
+func (h Harness) pluginCacheDir() string {
+	dir := ""
+	for _, e := range append(os.Environ(), h.Envs...) {
+		if v, ok := strings.CutPrefix(e, "TF_PLUGIN_CACHE_DIR="); ok {
+			dir = v
+		}
+	}
+	if dir != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(h.Dir, dir)
+	}
+	return dir
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+)
+
+func TestCacheInstalls(t *testing.T) {
+	main := `resource "aws_instance" "web" {}`
+	lock := heredoc.Doc(`
+		provider "registry.terraform.io/hashicorp/aws" {
+		  version = "5.0.0"
+		}
+
+		provider "registry.terraform.io/hashicorp/random" {
+		  version = "3.6.0"
+		}
+	`)
+	cached := filepath.Join("registry.terraform.io/hashicorp/aws/5.0.0", runtime.GOOS+"_"+runtime.GOARCH)
+
+	type want struct {
+		install []string
+		ok      bool
+	}
+
+	cases := map[string]struct {
+		reason  string
+		changed map[string]string
+		remove  []string
+		args    []string
+		noCache bool
+
+		// initialized is true if the changed files were initialized.
+		initialized bool
+		want        want
+	}{
+		"Unchanged": {
+			reason: "Init should only install locked providers that aren't in the plugin cache.",
+			want: want{
+				install: []string{"registry.terraform.io/hashicorp/random"},
+				ok:      true,
+			},
+		},
+		"NoPluginCache": {
+			reason:  "Init should install all locked providers if there is no plugin cache.",
+			noCache: true,
+			want: want{
+				install: []string{"registry.terraform.io/hashicorp/aws", "registry.terraform.io/hashicorp/random"},
+				ok:      true,
+			},
+		},
+		"FirstInit": {
+			reason: "The first init of a configuration should use the plugin cache, installing locked providers that aren't in it.",
+			remove: []string{initStateFile},
+			want: want{
+				install: []string{"registry.terraform.io/hashicorp/random"},
+				ok:      true,
+			},
+		},
+		"FirstInitNoLockFile": {
+			reason: "The first init of a configuration without a dependency lock file may install any version of its providers.",
+			remove: []string{".terraform", ".terraform.lock.hcl"},
+			want: want{
+				install: []string{"registry.terraform.io/hashicorp/aws"},
+				ok:      true,
+			},
+		},
+		"ProvidersChanged": {
+			reason:  "Init should install providers that were added since the last init.",
+			changed: map[string]string{"main.tf": main + "\nresource \"null_resource\" \"a\" {}\n"},
+			want: want{
+				install: []string{"registry.terraform.io/hashicorp/null", "registry.terraform.io/hashicorp/random"},
+				ok:      true,
+			},
+		},
+		"RequiredProviders": {
+			reason: "Providers should be identified by the sources they're required from, and the built-in terraform provider is never installed.",
+			changed: map[string]string{
+				"versions.tf": heredoc.Doc(`
+					terraform {
+					  required_providers {
+					    acme = {
+					      source  = "example.com/Acme/acme"
+					      version = "~> 1.0"
+					    }
+					    kubernetes = ">= 2.0"
+					  }
+					}
+				`),
+				"acme.tf":               `resource "acme_thing" "a" {}`,
+				"modules/local/main.tf": `resource "terraform_data" "a" {}`,
+			},
+			want: want{
+				install: []string{
+					"example.com/acme/acme",
+					"registry.terraform.io/hashicorp/kubernetes",
+					"registry.terraform.io/hashicorp/random",
+				},
+				ok: true,
+			},
+		},
+		"Upgrade": {
+			reason: "An upgrade may install new versions of any provider.",
+			args:   []string{"-upgrade"},
+			want: want{
+				install: []string{"registry.terraform.io/hashicorp/aws", "registry.terraform.io/hashicorp/random"},
+				ok:      true,
+			},
+		},
+		"FromModule": {
+			reason: "We can't tell what a configuration copied from a module will install.",
+			args:   []string{"-from-module=git::https://example.org/module.git"},
+			want:   want{ok: false},
+		},
+		"RemoteModuleNotInstalled": {
+			reason:  "We can't tell what a configuration that calls a module that isn't installed will install.",
+			changed: map[string]string{"vpc.tf": `module "vpc" { source = "terraform-aws-modules/vpc/aws" }`},
+			want:    want{ok: false},
+		},
+		"RemoteModuleInstalled": {
+			reason: "Init should install the providers of installed modules.",
+			changed: map[string]string{
+				"vpc.tf":                               `module "vpc" { source = "terraform-aws-modules/vpc/aws" }`,
+				".terraform/modules/modules.json":      `{}`,
+				".terraform/modules/vpc/main.tf":       `resource "tls_private_key" "a" {}`,
+				".terraform/providers/ignored/main.tf": `resource "ignored_thing" "a" {}`,
+			},
+			initialized: true,
+			want: want{
+				install: []string{"registry.terraform.io/hashicorp/random", "registry.terraform.io/hashicorp/tls"},
+				ok:      true,
+			},
+		},
+	}
+
+	write := func(t *testing.T, dir string, files map[string]string) {
+		t.Helper()
+		for p, data := range files {
+			if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, p), []byte(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			cache := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(cache, cached), 0700); err != nil {
+				t.Fatal(err)
+			}
+			h := Harness{Dir: dir, Envs: []string{"TF_PLUGIN_CACHE_DIR=" + cache}}
+			if tc.noCache {
+				h.Envs = []string{"TF_PLUGIN_CACHE_DIR="}
+			}
+
+			// Simulate a successful init.
+			write(t, dir, map[string]string{"main.tf": main, ".terraform.lock.hcl": lock})
+			s, err := h.initState(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Join(dir, ".terraform"), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := h.writeInitState(s); err != nil {
+				t.Fatal(err)
+			}
+
+			write(t, dir, tc.changed)
+			if tc.initialized {
+				s, err := h.initState(nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := h.writeInitState(s); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, p := range tc.remove {
+				if err := os.RemoveAll(filepath.Join(dir, p)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s, serr := h.initState(tc.args)
+			install, ok := h.cacheInstalls(tc.args, s, serr)
+			if diff := cmp.Diff(tc.want, want{install: install, ok: ok}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nh.cacheInstalls(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInitEnv(t *testing.T) {
+	t.Setenv("TF_PLUGIN_CACHE_DIR", "/tf/plugin-cache")
+
+	cases := map[string]struct {
+		reason string
+		shared bool
+		want   bool
+	}{
+		"Shared": {
+			reason: "Init should use the shared plugin cache if it may.",
+			shared: true,
+			want:   true,
+		},
+		"NotShared": {
+			reason: "Init should not use the shared plugin cache if it could replace a provider that is in use.",
+			shared: false,
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := Harness{Envs: []string{"TF_TOKEN_app_terraform_io=token"}}
+			env := h.initEnv(tc.shared)
+			got := slices.Contains(env, "TF_PLUGIN_CACHE_DIR=/tf/plugin-cache")
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nh.initEnv(...): -want plugin cache, +got plugin cache:\n%s", tc.reason, diff)
+			}
+			if !slices.Contains(env, "TF_TOKEN_app_terraform_io=token") {
+				t.Errorf("\n%s\nh.initEnv(...): want the Harness's environment variables", tc.reason)
+			}
+		})
+	}
+}
+
+func TestInitAcquiresBeforeLocking(t *testing.T) {
+	errBoom := errors.New("boom")
+	provider := "registry.terraform.io/hashicorp/random/3.6.0"
+
+	dir := t.TempDir()
+	h := Harness{Path: "terraform", Dir: dir, UsePluginCache: true, Envs: []string{"TF_PLUGIN_CACHE_DIR=" + t.TempDir()}}
+
+	// Simulate a successful init, so that init may use the shared plugin
+	// cache to install the locked provider.
+	if err := os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(`provider "`+filepath.Dir(provider)+`" {
+  version = "3.6.0"
+}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := h.initState(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".terraform"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.writeInitState(s); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.cacheInstalls(nil, s, nil); !ok {
+		t.Fatal("h.cacheInstalls(...): want init to use the shared plugin cache")
+	}
+
+	// Another Workspace's init is installing the same provider.
+	unlock := installMu.Lock(provider)
+	defer unlock()
+
+	// Init must wait for its turn before it waits for the plugin cache, so it
+	// should ask the Limiter for a turn even though the plugin cache is
+	// locked.
+	h.Limiter = limiterFn(func(_ context.Context) (func(), error) { return nil, errBoom })
+	done := make(chan error, 1)
+	go func() { done <- h.Init(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if diff := cmp.Diff(errors.Wrap(errBoom, errAcquire), err, test.EquateErrors()); diff != "" {
+			t.Errorf("h.Init(...): -want error, +got error:\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("h.Init(...): locked the plugin cache before acquiring the Limiter")
+	}
+}
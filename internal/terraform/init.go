@@ -0,0 +1,281 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/pkg/errors"
+)
+
+// Error strings.
+const (
+	errJSONConfig   = "cannot determine the requirements of JSON configuration files"
+	errNativeSyntax = "configuration file is not in the native syntax"
+)
+
+// initStateFile records the requirements of the configuration that was last
+// successfully initialized.
+const initStateFile = ".terraform/crossplane-init-state.json"
+
+// An initMode is a kind of terraform init.
+type initMode int
+
+const (
+	// initFull runs terraform init.
+	initFull initMode = iota
+
+	// initNoBackend runs terraform init -backend=false. It installs modules
+	// and providers, but uses the previously initialized backend.
+	initNoBackend
+
+	// initNone doesn't run terraform init.
+	initNone
+)
+
+// initState summarizes the parts of a configuration that determine whether
+// and how terraform init must be run.
+type initState struct {
+	// Backend is a hash of the init arguments, backend configuration files,
+	// and terraform blocks of the root module.
+	Backend string `json:"backend"`
+
+	// Modules is a hash of the names, sources and versions of all module
+	// calls.
+	Modules string `json:"modules"`
+
+	// Providers is a hash of the providers that are configured or used by
+	// resources and data sources, and of the terraform blocks of local
+	// modules.
+	Providers string `json:"providers"`
+
+	// moduleCalls is true if the configuration calls any modules.
+	moduleCalls bool
+}
+
+var lockedProvider = regexp.MustCompile(`(?m)^provider\s+"([^"]+)"\s*\{\s*version\s*=\s*"([^"]+)"`)
+
+// forcesInit returns true if the supplied init arguments must always be
+// honoured by running terraform init.
+func forcesInit(args []string) bool {
+	for _, a := range args {
+		if strings.HasPrefix(a, "-from-module") || strings.HasPrefix(a, "-upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// initState returns the current init state of the configuration in the
+// harness's directory.
+func (h Harness) initState(args []string) (initState, error) { //nolint:gocyclo // Mostly simple accumulation of hash inputs.
+	backend := sha256.New()
+	for _, a := range args {
+		backend.Write([]byte(a + "\x00"))
+		f, ok := strings.CutPrefix(a, "-backend-config=")
+		if !ok {
+			continue
+		}
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(h.Dir, f)
+		}
+		// Backend config may also be supplied as key=value pairs.
+		if b, err := os.ReadFile(f); err == nil { //nolint:gosec // We want to read backend config files.
+			backend.Write(b)
+		}
+	}
+
+	var modules, providers []string
+	err := filepath.WalkDir(h.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == ".terraform" || d.Name() == ".git") {
+			return filepath.SkipDir
+		}
+		if strings.HasSuffix(p, ".tf.json") {
+			return errors.New(errJSONConfig)
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".tf") {
+			return nil
+		}
+		src, err := os.ReadFile(p) //nolint:gosec // We want to read configuration files.
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(h.Dir, p)
+		blocks, err := topLevelBlocks(rel, src)
+		if err != nil {
+			return errors.Wrap(err, rel)
+		}
+		root := filepath.Dir(p) == filepath.Clean(h.Dir)
+		for _, b := range blocks {
+			switch b.typ {
+			case "terraform":
+				if root {
+					backend.Write(b.body)
+					continue
+				}
+				providers = append(providers, filepath.Dir(rel)+":"+string(b.body))
+			case "provider":
+				providers = append(providers, strings.Join(b.labels, "."))
+			case "resource", "data", "ephemeral":
+				if len(b.labels) > 0 {
+					providers = append(providers, strings.SplitN(b.labels[0], "_", 2)[0])
+				}
+			case "module":
+				m := filepath.Dir(rel) + ":" + strings.Join(b.labels, ".")
+				for _, arg := range []string{"source", "version"} {
+					if v, ok := b.attrs[arg]; ok {
+						m += "," + arg + "=" + v
+					}
+				}
+				modules = append(modules, m)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return initState{}, err
+	}
+
+	return initState{
+		Backend:     hex.EncodeToString(backend.Sum(nil)),
+		Modules:     hashSet(modules),
+		Providers:   hashSet(providers),
+		moduleCalls: len(modules) > 0,
+	}, nil
+}
+
+// initMode returns the kind of terraform init required to initialize the
+// configuration in the harness's directory, which has the supplied state.
+func (h Harness) initMode(s initState) initMode {
+	last, ok := h.lastInitState()
+	if !ok || last.Backend != s.Backend {
+		return initFull
+	}
+	if last.Modules != s.Modules || last.Providers != s.Providers {
+		return initNoBackend
+	}
+	if s.moduleCalls {
+		if _, err := os.Stat(filepath.Join(h.Dir, ".terraform", "modules", "modules.json")); err != nil {
+			return initNoBackend
+		}
+	}
+	if !h.providersInstalled() {
+		return initNoBackend
+	}
+	return initNone
+}
+
+// lastInitState returns the state of the configuration that was last
+// successfully initialized, if any.
+func (h Harness) lastInitState() (initState, bool) {
+	b, err := os.ReadFile(filepath.Join(h.Dir, initStateFile))
+	if err != nil {
+		return initState{}, false
+	}
+	last := initState{}
+	if err := json.Unmarshal(b, &last); err != nil {
+		return initState{}, false
+	}
+	return last, true
+}
+
+// providersInstalled returns true if every provider in the dependency lock
+// file is installed for this platform.
+func (h Harness) providersInstalled() bool {
+	b, err := os.ReadFile(filepath.Join(h.Dir, ".terraform.lock.hcl"))
+	if err != nil {
+		return false
+	}
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	for _, p := range lockedProvider.FindAllSubmatch(b, -1) {
+		// Providers installed from the plugin cache are symlinks, so this
+		// also checks that they're still in the cache.
+		if _, err := os.Stat(filepath.Join(h.Dir, ".terraform", "providers", string(p[1]), string(p[2]), platform)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeInitState records the supplied state as that of the configuration that
+// was last successfully initialized.
+func (h Harness) writeInitState(s initState) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(h.Dir, initStateFile), b, 0600)
+}
+
+func hashSet(s []string) string {
+	sort.Strings(s)
+	h := sha256.New()
+	for i, e := range s {
+		if i > 0 && e == s[i-1] {
+			continue
+		}
+		h.Write([]byte(e + "\x00"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// A block is a top-level block of a Terraform configuration file in the
+// native syntax.
+type block struct {
+	typ    string
+	labels []string
+	body   []byte
+
+	// attrs are the source of the expressions of the block's arguments.
+	attrs map[string]string
+}
+
+// topLevelBlocks returns the top-level blocks of the supplied configuration
+// file.
+func topLevelBlocks(filename string, src []byte) ([]block, error) {
+	f, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, errors.New(errNativeSyntax)
+	}
+	blocks := make([]block, 0, len(body.Blocks))
+	for _, b := range body.Blocks {
+		attrs := make(map[string]string, len(b.Body.Attributes))
+		for name, a := range b.Body.Attributes {
+			attrs[name] = string(a.Expr.Range().SliceBytes(src))
+		}
+		blocks = append(blocks, block{typ: b.Type, labels: b.Labels, body: b.Body.SrcRange.SliceBytes(src), attrs: attrs})
+	}
+	return blocks, nil
+}
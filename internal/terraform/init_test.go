@@ -0,0 +1,268 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTopLevelBlocks(t *testing.T) {
+	type want struct {
+		blocks []string
+		attrs  []string
+		err    bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		src    string
+		want   want
+	}{
+		"Blocks": {
+			reason: "We should return the type and labels of each top-level block.",
+			src: heredoc.Doc(`
+				# A comment { with a brace
+				terraform {
+				  backend "kubernetes" {}
+				}
+
+				/* Another comment } */
+				resource "aws_instance" "web" {
+				  tags = { Name = "web" }
+				}
+
+				module vpc {
+				  source = "./vpc"
+				}
+			`),
+			want: want{blocks: []string{"terraform", "resource.aws_instance.web", "module.vpc"}, attrs: []string{`source="./vpc"`}},
+		},
+		"StringsAndTemplates": {
+			reason: "Braces within strings, templates and heredocs should not end a block.",
+			src: heredoc.Doc(`
+				locals {
+				  a = "}"
+				  b = "${jsonencode({ a = "}" })}"
+				  c = "$${"
+				  d = <<-EOT
+				    }
+				  EOT
+				}
+
+				output "a" {
+				  value = local.a
+				}
+			`),
+			want: want{blocks: []string{"locals", "output.a"}},
+		},
+		"Unbalanced": {
+			reason: "We should return an error if a block is not closed.",
+			src:    `resource "a" "b" {`,
+			want:   want{err: true},
+		},
+		"NestedModuleArguments": {
+			reason: "Only the arguments of a block should be returned, not those of its nested blocks.",
+			src: heredoc.Doc(`
+				module "vpc" {
+				  source = "./vpc"
+				  lifecycle {
+				    version = "ignored"
+				  }
+				}
+			`),
+			want: want{blocks: []string{"module.vpc"}, attrs: []string{`source="./vpc"`}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			blocks, err := topLevelBlocks("main.tf", []byte(tc.src))
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Fatalf("\n%s\ntopLevelBlocks(...): -want error, +got error:\n%s\n%v", tc.reason, diff, err)
+			}
+			var got, attrs []string
+			for _, b := range blocks {
+				id := b.typ
+				for _, l := range b.labels {
+					id += "." + l
+				}
+				got = append(got, id)
+				if b.typ != "module" {
+					continue
+				}
+				for k, v := range b.attrs {
+					attrs = append(attrs, k+"="+v)
+				}
+			}
+			if diff := cmp.Diff(tc.want.blocks, got); diff != "" {
+				t.Errorf("\n%s\ntopLevelBlocks(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.attrs, attrs); diff != "" {
+				t.Errorf("\n%s\ntopLevelBlocks(...): -want module arguments, +got module arguments:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInitMode(t *testing.T) {
+	main := heredoc.Doc(`
+		terraform {
+		  backend "kubernetes" {}
+		}
+
+		module "vpc" {
+		  source  = "terraform-aws-modules/vpc/aws"
+		  version = "5.0.0"
+		  name    = "vpc"
+		}
+
+		resource "aws_instance" "web" {}
+	`)
+	lock := heredoc.Doc(`
+		provider "registry.terraform.io/hashicorp/aws" {
+		  version = "5.0.0"
+		}
+	`)
+	installed := map[string]string{
+		".terraform.lock.hcl":             lock,
+		".terraform/modules/modules.json": "{}",
+		".terraform/providers/registry.terraform.io/hashicorp/aws/5.0.0/" + runtime.GOOS + "_" + runtime.GOARCH + "/provider": "binary",
+	}
+
+	cases := map[string]struct {
+		reason  string
+		changed map[string]string
+		remove  []string
+		want    initMode
+	}{
+		"NeverInitialized": {
+			reason:  "A configuration that was never initialized should be fully initialized.",
+			changed: map[string]string{"main.tf": main},
+			remove:  []string{initStateFile},
+			want:    initFull,
+		},
+		"Unchanged": {
+			reason: "A configuration whose requirements haven't changed should not be initialized.",
+			want:   initNone,
+		},
+		"ModuleInputChanged": {
+			reason:  "Changes to the inputs of a module call should not require initialization.",
+			changed: map[string]string{"main.tf": strings.Replace(main, `name    = "vpc"`, `name    = "other"`, 1)},
+			want:    initNone,
+		},
+		"ModuleVersionChanged": {
+			reason: "Changes to the version of a module call should require modules to be installed.",
+			changed: map[string]string{"main.tf": heredoc.Doc(`
+				terraform {
+				  backend "kubernetes" {}
+				}
+
+				module "vpc" {
+				  source  = "terraform-aws-modules/vpc/aws"
+				  version = "5.1.0"
+				}
+
+				resource "aws_instance" "web" {}
+			`)},
+			want: initNoBackend,
+		},
+		"ProviderAdded": {
+			reason:  "Using a new provider should require providers to be installed.",
+			changed: map[string]string{"main.tf": main + "\nresource \"random_id\" \"a\" {}\n"},
+			want:    initNoBackend,
+		},
+		"LocalModuleProviderAdded": {
+			reason:  "Using a new provider in a local module should require providers to be installed.",
+			changed: map[string]string{"modules/a/main.tf": "resource \"random_id\" \"a\" {}\n"},
+			want:    initNoBackend,
+		},
+		"ProviderNotInstalled": {
+			reason: "A configuration whose providers are no longer installed should have them installed.",
+			remove: []string{".terraform/providers"},
+			want:   initNoBackend,
+		},
+		"BackendChanged": {
+			reason:  "Changes to the backend should require full initialization.",
+			changed: map[string]string{"main.tf": "terraform {\n  backend \"s3\" {}\n}\n"},
+			want:    initFull,
+		},
+		"BackendConfigChanged": {
+			reason:  "Changes to a backend configuration file should require full initialization.",
+			changed: map[string]string{"backend.tfbackend": "b = 2"},
+			want:    initFull,
+		},
+		"JSONConfiguration": {
+			reason:  "A configuration that includes JSON files should be fully initialized.",
+			changed: map[string]string{"main.tf.json": "{}"},
+			want:    initFull,
+		},
+	}
+
+	write := func(t *testing.T, dir string, files map[string]string) {
+		t.Helper()
+		for p, data := range files {
+			if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, p), []byte(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	args := []string{"-backend-config=backend.tfbackend"}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			h := Harness{Dir: dir}
+
+			// Simulate a successful init.
+			write(t, dir, map[string]string{"main.tf": main, "backend.tfbackend": "b = 1"})
+			write(t, dir, installed)
+			s, err := h.initState(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := h.writeInitState(s); err != nil {
+				t.Fatal(err)
+			}
+
+			write(t, dir, tc.changed)
+			for _, p := range tc.remove {
+				if err := os.RemoveAll(filepath.Join(dir, p)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := initFull
+			if s, err := h.initState(args); err == nil {
+				got = h.initMode(s)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nh.initMode(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
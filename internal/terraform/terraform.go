@@ -22,17 +22,21 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"syscall"
 
 	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
@@ -45,9 +49,11 @@ const (
 	errWriteVarFile     = "cannot write tfvars file"
 	errFmtInvalidConfig = "invalid Terraform configuration: found %d errors"
 	errRunCommand       = "shutdown while running terraform command"
+	errAcquire          = "shutdown while waiting to run terraform command"
 	errSigTerm          = "error sending SIGTERM to child process"
 	errWaitTerm         = "error waiting for child process to terminate"
 	errWriteLogs        = "error writing terraform logs to stdout"
+	errChecksum         = "cannot calculate workspace checksum"
 
 	tfDefault = "default"
 )
@@ -60,12 +66,12 @@ var tfError = regexp.MustCompile(`Error: (.+)\n`)
 
 // Classify errors returned from the Terraform CLI by inspecting its stderr.
 func Classify(err error) error {
-	ee := &exec.ExitError{}
-	if !errors.As(err, &ee) {
+	stderr, ok := stderrOf(err)
+	if !ok {
 		return err
 	}
 
-	summary, base64FullErr, err := formatTerraformErrorOutput(string(ee.Stderr))
+	summary, base64FullErr, err := formatTerraformErrorOutput(string(stderr))
 	if err != nil {
 		return err
 	}
@@ -75,6 +81,33 @@ func Classify(err error) error {
 	return errors.New(fmt.Sprintf(formatString, summary, base64FullErr))
 }
 
+// An ExitError is returned by a Runner when Terraform exits with a non-zero
+// exit code.
+type ExitError struct {
+	// Code is the exit code of Terraform.
+	Code int
+
+	// Stderr is the error output of Terraform. Runners that can't separate
+	// Terraform's error output from its standard output include both.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	return "exit status " + strconv.Itoa(e.Code)
+}
+
+// stderrOf returns the error output of the Terraform command that returned
+// the supplied error, if it exited with a non-zero exit code.
+func stderrOf(err error) ([]byte, bool) {
+	if ee := (&exec.ExitError{}); errors.As(err, &ee) {
+		return ee.Stderr, true
+	}
+	if ee := (&ExitError{}); errors.As(err, &ee) {
+		return ee.Stderr, true
+	}
+	return nil, false
+}
+
 // Format Terraform error output as gzipped and base64 encoded string
 func formatTerraformErrorOutput(errorOutput string) (string, string, error) {
 	// Gzip compress the output and base64 encode it.
@@ -135,6 +168,19 @@ type Harness struct {
 	// Environment Variables
 	Envs []string
 
+	// ChecksumIgnore are patterns of paths, relative to Dir, to exclude
+	// from the checksum of the workspace in addition to
+	// DefaultChecksumIgnore.
+	ChecksumIgnore []string
+
+	// Runner runs the plan, apply and destroy commands. They're run by the
+	// provider process if it is nil.
+	Runner Runner
+
+	// Limiter bounds how many Terraform commands run at once. They're not
+	// bounded if it is nil.
+	Limiter Limiter
+
 	// TODO(negz): Harness is a subset of exec.Cmd. If callers need more insight
 	// into what the underlying Terraform binary is doing (e.g. for debugging)
 	// we could consider allowing them to attach io.Writers to Stdout and Stdin
@@ -143,6 +189,31 @@ type Harness struct {
 	// logic that copies Stderr into an *exec.ExitError.
 }
 
+// A Runner runs a Terraform command that would otherwise be run by the
+// provider process, for example in a Kubernetes Job. It returns the standard
+// output of the command, or an *ExitError if it exits with a non-zero exit
+// code.
+type Runner interface {
+	Run(ctx context.Context, cmd *exec.Cmd) ([]byte, error)
+}
+
+// A Limiter bounds how many Terraform commands run at once.
+type Limiter interface {
+	// Acquire blocks until a command may run, or the supplied context is
+	// done. The returned function must be called once the command is done.
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
+// RegistryTokenEnv returns an environment variable that configures Terraform
+// to authenticate to the supplied registry hostname using the supplied API
+// token. Periods in the hostname are encoded as underscores, and dashes as
+// double underscores, per
+// https://developer.hashicorp.com/terraform/cli/config/config-file#environment-variable-credentials
+func RegistryTokenEnv(hostname, token string) string {
+	h := strings.ReplaceAll(strings.ToLower(hostname), "-", "__")
+	return "TF_TOKEN_" + strings.ReplaceAll(h, ".", "_") + "=" + token
+}
+
 type initOptions struct {
 	args []string
 }
@@ -174,38 +245,94 @@ func InitArgsToString(o []InitOption) []string {
 	return io.args
 }
 
-// RWMutex protects the terraform shared cache from corruption. If an init is
-// performed, it requires a write lock. Only one write lock at a time. If another
-// action is performed, a read lock is acquired. More than one read locks can be acquired.
-// This prevents an init from inadvertently changing a plugin while it's in use.
-// Prevents issues with `text file busy` errors.
-var rwmutex = &sync.RWMutex{}
-
-// Init initializes a Terraform configuration.
+// Init initializes a Terraform configuration. Init is skipped if the
+// configuration's backend, module calls and providers haven't changed since it
+// was last initialized, and its modules and providers are still installed.
+// Only modules and providers are installed, per init -backend=false, if the
+// backend hasn't changed. Init is never skipped when modules or providers
+// must be upgraded.
 func (h Harness) Init(ctx context.Context, o ...InitOption) error {
-	args := append([]string{"init", "-input=false", "-no-color"}, InitArgsToString(o)...)
+	iargs := InitArgsToString(o)
+	state, serr := h.initState(iargs)
+	if serr == nil && !forcesInit(iargs) {
+		switch h.initMode(state) {
+		case initNone:
+			h.debug("Configuration is already initialized - skip running terraform init")
+			return nil
+		case initNoBackend:
+			h.debug("Backend is already initialized - running terraform init -backend=false")
+			iargs = append(iargs, "-backend=false")
+		case initFull:
+		}
+	}
+
+	// An init whose providers can't be determined can't lock them, so it
+	// installs providers into the Workspace's own .terraform directory
+	// instead of the shared plugin cache.
+	var install []string
+	shared := false
+	if h.UsePluginCache {
+		if install, shared = h.cacheInstalls(iargs, state, serr); !shared {
+			h.debug("Cannot determine which providers terraform init will install - not using the shared plugin cache")
+		}
+	}
+
+	args := append([]string{"init", "-input=false", "-no-color"}, iargs...)
 	cmd := exec.Command(h.Path, args...) //nolint:gosec
 	cmd.Dir = h.Dir
-	for _, e := range os.Environ() {
-		if strings.Contains(e, "TF_PLUGIN_CACHE_DIR") {
-			if !h.UsePluginCache {
-				continue
-			}
-		}
-		cmd.Env = append(cmd.Env, e)
+	cmd.Env = h.initEnv(shared)
+
+	// Wait for the Limiter before locking the plugin cache, so that an init
+	// waiting for its turn doesn't block other Workspaces' inits that install
+	// the same providers.
+	release, err := h.acquire(ctx)
+	if err != nil {
+		return err
 	}
-	cmd.Env = append(cmd.Env, "TF_CLI_CONFIG_FILE=./.terraformrc")
-	if len(h.Envs) > 0 {
-		cmd.Env = append(cmd.Env, h.Envs...)
+	defer release()
+	if shared {
+		unlock := installMu.Lock(install...)
+		defer unlock()
 	}
 
-	if h.UsePluginCache {
-		rwmutex.Lock()
-		defer rwmutex.Unlock()
+	// Forget the previous init state first, so that a configuration is fully
+	// initialized next time if this init is interrupted, e.g. because the
+	// provider restarted while its working directory is persisted.
+	if err := os.Remove(filepath.Join(h.Dir, initStateFile)); err != nil && !os.IsNotExist(err) {
+		h.debug("Cannot remove init state - terraform init may be skipped next time", "error", err)
+	}
+	if _, err := runCommand(ctx, cmd); err != nil {
+		return Classify(err)
+	}
+	if serr != nil {
+		// We couldn't determine the state of the configuration, so we run a
+		// full init next time.
+		return nil
+	}
+	if err := h.writeInitState(state); err != nil {
+		h.debug("Cannot record init state - terraform init will run next time", "error", err)
 	}
+	return nil
+}
 
-	_, err := runCommand(ctx, cmd)
-	return Classify(err)
+// initEnv returns the environment of terraform init. The plugin cache is only
+// configured if init may use the shared plugin cache.
+func (h Harness) initEnv(sharedPluginCache bool) []string {
+	env := make([]string, 0, len(os.Environ())+len(h.Envs)+1)
+	for _, e := range os.Environ() {
+		if strings.Contains(e, "TF_PLUGIN_CACHE_DIR") && !sharedPluginCache {
+			continue
+		}
+		env = append(env, e)
+	}
+	env = append(env, "TF_CLI_CONFIG_FILE=./.terraformrc")
+	return append(env, h.Envs...)
+}
+
+func (h Harness) debug(msg string, kv ...any) {
+	if h.Logger != nil {
+		h.Logger.Debug(msg, kv...)
+	}
 }
 
 // Validate a Terraform configuration. Note that there may be interplay between
@@ -226,7 +353,7 @@ func (h Harness) Validate(ctx context.Context) error {
 
 	// The validate command returns zero for a valid module and non-zero for an
 	// invalid module, but it returns its JSON to stdout either way.
-	out, err := runCommand(ctx, cmd)
+	out, err := h.exec(ctx, cmd)
 
 	r := &result{}
 	if jerr := json.Unmarshal(out, r); jerr != nil {
@@ -254,7 +381,7 @@ func (h Harness) Workspace(ctx context.Context, name string) error {
 		cmd.Env = append(os.Environ(), h.Envs...)
 	}
 
-	if _, err := runCommand(ctx, cmd); err == nil {
+	if _, err := h.exec(ctx, cmd); err == nil {
 		// We successfully selected the workspace; we're done.
 		return nil
 	}
@@ -268,12 +395,7 @@ func (h Harness) Workspace(ctx context.Context, name string) error {
 		cmd.Env = append(os.Environ(), h.Envs...)
 	}
 
-	if h.UsePluginCache {
-		rwmutex.RLock()
-		defer rwmutex.RUnlock()
-	}
-
-	_, err := runCommand(ctx, cmd)
+	_, err := h.exec(ctx, cmd)
 	return Classify(err)
 }
 
@@ -285,7 +407,7 @@ func (h Harness) DeleteCurrentWorkspace(ctx context.Context) error {
 		cmd.Env = append(os.Environ(), h.Envs...)
 	}
 
-	n, err := runCommand(ctx, cmd)
+	n, err := h.exec(ctx, cmd)
 	if err != nil {
 		return Classify(err)
 	}
@@ -305,29 +427,85 @@ func (h Harness) DeleteCurrentWorkspace(ctx context.Context) error {
 		cmd.Env = append(os.Environ(), h.Envs...)
 	}
 
-	if h.UsePluginCache {
-		rwmutex.RLock()
-		defer rwmutex.RUnlock()
-	}
-
-	_, err = runCommand(ctx, cmd)
+	_, err = h.exec(ctx, cmd)
 	if err == nil {
 		// We successfully deleted the workspace; we're done.
 		return nil
 	}
-	// TODO(bobh66) The working directory could be deleted here instead of waiting for GC to clean it up
 	return Classify(err)
 }
 
-// GenerateChecksum calculates the md5sum of the workspace (excluding installed providers) to see if terraform init needs to run
+// DefaultChecksumIgnore are the paths, relative to the working directory,
+// that are always excluded from its checksum. They're either installed or
+// written by Terraform, or written by the Harness.
+var DefaultChecksumIgnore = []string{
+	".git",
+	".terraform/providers",
+	"terraform.tfstate",
+	"terraform.tfstate.backup",
+	".terraform.tfstate.lock.info",
+	varFilePrefix + "*",
+}
+
+// GenerateChecksum calculates a checksum of the workspace to see if terraform
+// init needs to run. The checksum is a sha256 hash of the path and sha256 hash
+// of each regular file in the workspace, in lexical order. Paths matching
+// DefaultChecksumIgnore or the Harness's ChecksumIgnore patterns, per
+// path.Match, are excluded. Directories that match a pattern are excluded
+// entirely.
 func (h Harness) GenerateChecksum(ctx context.Context) (string, error) {
-	command := "/usr/bin/find . -path ./.git -prune -o -path ./.terraform/providers -prune -o -type f -exec /usr/bin/md5sum {} + | LC_ALL=C /usr/bin/sort | /usr/bin/md5sum | /usr/bin/awk '{print $1}'"
-	cmd := exec.Command("/bin/sh", "-c", command) //nolint:gosec
-	cmd.Dir = h.Dir
+	ignore := append(append([]string{}, DefaultChecksumIgnore...), h.ChecksumIgnore...)
+
+	sum := sha256.New()
+	err := filepath.WalkDir(h.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(h.Dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && ignored(ignore, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p) //nolint:gosec // We want to read files in the workspace.
+		if err != nil {
+			return err
+		}
+		defer f.Close() //nolint:errcheck // We only read this file.
+		fsum := sha256.New()
+		if _, err := io.Copy(fsum, f); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(sum, "%x  %s\n", fsum.Sum(nil), rel)
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrap(err, errChecksum)
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
 
-	checksum, err := runCommand(ctx, cmd)
-	result := strings.ReplaceAll(string(checksum), "\n", "")
-	return result, Classify(err)
+// ignored returns true if the supplied slash separated path matches any of
+// the supplied patterns.
+func ignored(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // An OutputType of Terraform.
@@ -341,6 +519,7 @@ const (
 	OutputTypeBool
 	OutputTypeTuple
 	OutputTypeObject
+	OutputTypeMap
 )
 
 func outputType(t string) OutputType {
@@ -355,6 +534,8 @@ func outputType(t string) OutputType {
 		return OutputTypeTuple
 	case "object":
 		return OutputTypeObject
+	case "map":
+		return OutputTypeMap
 	default:
 		return OutputTypeUnknown
 	}
@@ -407,6 +588,35 @@ func (o Output) JSONValue() ([]byte, error) {
 	return json.Marshal(o.value)
 }
 
+// Flatten returns the output's value flattened into a map of attribute paths
+// to values. The values of object and map outputs are flattened recursively,
+// joining the names of nested attributes or keys with '.'. The value of any
+// other output, including that of a tuple, is returned as is under the empty
+// path.
+func (o Output) Flatten() map[string]any {
+	flat := map[string]any{}
+	if o.Type != OutputTypeObject && o.Type != OutputTypeMap {
+		flat[""] = o.value
+		return flat
+	}
+	flatten(flat, "", o.value)
+	return flat
+}
+
+func flatten(flat map[string]any, path string, v any) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		flat[path] = v
+		return
+	}
+	for k, mv := range m {
+		if path != "" {
+			k = path + "." + k
+		}
+		flatten(flat, k, mv)
+	}
+}
+
 // Outputs extracts outputs from Terraform state.
 func (h Harness) Outputs(ctx context.Context) ([]Output, error) {
 	cmd := exec.Command(h.Path, "output", "-json") //nolint:gosec
@@ -423,12 +633,7 @@ func (h Harness) Outputs(ctx context.Context) ([]Output, error) {
 
 	outputs := map[string]output{}
 
-	if h.UsePluginCache {
-		rwmutex.RLock()
-		defer rwmutex.RUnlock()
-	}
-
-	out, err := runCommand(ctx, cmd)
+	out, err := h.exec(ctx, cmd)
 	if jerr := json.Unmarshal(out, &outputs); jerr != nil {
 		// If stdout doesn't appear to be the JSON we expected we try to extract
 		// an error from stderr.
@@ -475,12 +680,7 @@ func (h Harness) Resources(ctx context.Context) ([]string, error) {
 		cmd.Env = append(os.Environ(), h.Envs...)
 	}
 
-	if h.UsePluginCache {
-		rwmutex.RLock()
-		defer rwmutex.RUnlock()
-	}
-
-	out, err := runCommand(ctx, cmd)
+	out, err := h.exec(ctx, cmd)
 	if err != nil {
 		return nil, Classify(err)
 	}
@@ -561,21 +761,18 @@ func (h Harness) Diff(ctx context.Context, o ...Option) (bool, error) {
 		cmd.Env = append(os.Environ(), h.Envs...)
 	}
 
-	// Note: the terraform lock is not used (see the -lock=false flag above) and the rwmutex is
-	// intentionally not locked here to avoid excessive blocking. See
-	// https://github.com/upbound/provider-terraform/issues/239#issuecomment-1921732682
+	// Note: the terraform lock is not used (see the -lock=false flag above).
 
 	// The -detailed-exitcode flag will make terraform plan return:
 	// 0 - Succeeded, diff is empty (no changes)
 	// 1 - Errored
 	// 2 - Succeeded, there is a diff
-	log, err := runCommand(ctx, cmd)
-	switch cmd.ProcessState.ExitCode() {
+	log, code, err := h.run(ctx, cmd)
+	switch code {
 	case 1:
-		ee := &exec.ExitError{}
-		errors.As(err, &ee)
+		stderr, _ := stderrOf(err)
 		if h.EnableTerraformCLILogging {
-			h.Logger.Info(string(ee.Stderr), "operation", "plan")
+			h.Logger.Info(string(stderr), "operation", "plan")
 		}
 	case 2:
 		if h.EnableTerraformCLILogging {
@@ -606,26 +803,20 @@ func (h Harness) Apply(ctx context.Context, o ...Option) error {
 		cmd.Env = append(os.Environ(), h.Envs...)
 	}
 
-	if h.UsePluginCache {
-		rwmutex.RLock()
-		defer rwmutex.RUnlock()
-	}
-
 	// In case of terraform apply
 	// 0 - Succeeded
 	// Non Zero output - Errored
 
-	log, err := runCommand(ctx, cmd)
-	switch cmd.ProcessState.ExitCode() {
+	log, code, err := h.run(ctx, cmd)
+	switch code {
 	case 0:
 		if h.EnableTerraformCLILogging {
 			h.Logger.Info(string(log), "operation", "apply")
 		}
 	default:
-		ee := &exec.ExitError{}
-		errors.As(err, &ee)
+		stderr, _ := stderrOf(err)
 		if h.EnableTerraformCLILogging {
-			h.Logger.Info(string(ee.Stderr), "operation", "apply")
+			h.Logger.Info(string(stderr), "operation", "apply")
 		}
 	}
 	return Classify(err)
@@ -651,26 +842,20 @@ func (h Harness) Destroy(ctx context.Context, o ...Option) error {
 		cmd.Env = append(os.Environ(), h.Envs...)
 	}
 
-	if h.UsePluginCache {
-		rwmutex.RLock()
-		defer rwmutex.RUnlock()
-	}
-
-	log, err := runCommand(ctx, cmd)
+	log, code, err := h.run(ctx, cmd)
 
 	// In case of terraform destroy
 	// 0 - Succeeded
 	// Non Zero output - Errored
-	switch cmd.ProcessState.ExitCode() {
+	switch code {
 	case 0:
 		if h.EnableTerraformCLILogging {
 			h.Logger.Info(string(log), "operation", "destroy")
 		}
 	default:
-		ee := &exec.ExitError{}
-		errors.As(err, &ee)
+		stderr, _ := stderrOf(err)
 		if h.EnableTerraformCLILogging {
-			h.Logger.Info(string(ee.Stderr), "operation", "destroy")
+			h.Logger.Info(string(stderr), "operation", "destroy")
 		}
 	}
 	return Classify(err)
@@ -682,6 +867,47 @@ type cmdResult struct {
 	err error
 }
 
+// run the supplied command using the Harness's Runner, if any, and return its
+// output and exit code. The exit code is -1 if the command didn't exit.
+func (h Harness) run(ctx context.Context, cmd *exec.Cmd) ([]byte, int, error) {
+	if h.Runner == nil {
+		out, err := h.exec(ctx, cmd)
+		return out, cmd.ProcessState.ExitCode(), err
+	}
+	release, err := h.acquire(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer release()
+	out, err := h.Runner.Run(ctx, cmd)
+	if ee := (&ExitError{}); errors.As(err, &ee) {
+		return out, ee.Code, err
+	}
+	if err != nil {
+		return out, -1, err
+	}
+	return out, 0, nil
+}
+
+// exec runs the supplied command in the provider process, once the Limiter
+// allows it.
+func (h Harness) exec(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	release, err := h.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return runCommand(ctx, cmd)
+}
+
+func (h Harness) acquire(ctx context.Context) (func(), error) {
+	if h.Limiter == nil {
+		return func() {}, nil
+	}
+	release, err := h.Limiter.Acquire(ctx)
+	return release, errors.Wrap(err, errAcquire)
+}
+
 // runCommand executes the requested command and sends the process SIGTERM if the context finishes before the command
 func runCommand(ctx context.Context, c *exec.Cmd) ([]byte, error) {
 	ch := make(chan cmdResult, 1)
@@ -188,6 +188,11 @@ func TestOutputs(t *testing.T) {
 			want: want{
 				outputs: []Output{
 					{Name: "bool", Type: OutputTypeBool, value: true},
+					{
+						Name:  "map",
+						Type:  OutputTypeMap,
+						value: map[string]any{"such": "map"},
+					},
 					{Name: "number", Type: OutputTypeNumber, value: float64(42)},
 					{
 						Name:  "object",
@@ -492,7 +497,7 @@ func TestGenerateChecksum(t *testing.T) {
 			module: "testdata/outputmodule",
 			ctx:    context.Background(),
 			want: want{
-				output: "d41d8cd98f00b204e9800998ecf8427e",
+				output: "e125af9f8a70e303a2139793fa516996d729b9975b8837c06f067723124fef3b",
 			},
 		},
 	}
@@ -17,12 +17,17 @@ limitations under the License.
 package terraform
 
 import (
+	"context"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
 )
 
 func TestOutputStringValue(t *testing.T) {
@@ -158,6 +163,65 @@ func TestOutputJSONValue(t *testing.T) {
 	}
 }
 
+func TestOutputFlatten(t *testing.T) {
+	cases := map[string]struct {
+		o    Output
+		want map[string]any
+	}{
+		"ValueIsString": {
+			o:    Output{value: "imastring!"},
+			want: map[string]any{"": "imastring!"},
+		},
+		"ValueIsTuple": {
+			o:    Output{value: []any{"imastring", 42.0}},
+			want: map[string]any{"": []any{"imastring", 42.0}},
+		},
+		"ValueIsObject": {
+			o: Output{Type: OutputTypeObject, value: map[string]any{
+				"host": "db.example.org",
+				"port": 5432.0,
+				"tls": map[string]any{
+					"enabled": true,
+				},
+				"replicas": []any{"a", "b"},
+			}},
+			want: map[string]any{
+				"host":        "db.example.org",
+				"port":        5432.0,
+				"tls.enabled": true,
+				"replicas":    []any{"a", "b"},
+			},
+		},
+		"ValueIsEmptyObject": {
+			o:    Output{Type: OutputTypeObject, value: map[string]any{}},
+			want: map[string]any{},
+		},
+		"ValueIsMapOfStrings": {
+			o: Output{Type: OutputTypeMap, value: map[string]any{
+				"us-east-1": "vpc-1",
+				"eu-west-1": "vpc-2",
+			}},
+			want: map[string]any{
+				"us-east-1": "vpc-1",
+				"eu-west-1": "vpc-2",
+			},
+		},
+		"ValueIsTupleOfObjects": {
+			o:    Output{Type: OutputTypeTuple, value: []any{map[string]any{"a": "b"}}},
+			want: map[string]any{"": []any{map[string]any{"a": "b"}}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.o.Flatten()
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\no.Flatten(): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestClassify(t *testing.T) {
 	tferrs := make(map[string]error)
 	expectedOutput := make(map[string]error)
@@ -288,3 +352,174 @@ func TestFormatTerraformErrorOutput(t *testing.T) {
 		)
 	}
 }
+
+func TestRegistryTokenEnv(t *testing.T) {
+	cases := map[string]struct {
+		hostname string
+		want     string
+	}{
+		"Periods": {
+			hostname: "app.terraform.io",
+			want:     "TF_TOKEN_app_terraform_io=token",
+		},
+		"Dashes": {
+			hostname: "my-registry.example.org",
+			want:     "TF_TOKEN_my__registry_example_org=token",
+		},
+		"UpperCase": {
+			hostname: "Registry.Example.org",
+			want:     "TF_TOKEN_registry_example_org=token",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := RegistryTokenEnv(tc.hostname, "token")
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("RegistryTokenEnv(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateChecksumIgnore(t *testing.T) {
+	base := map[string]string{
+		"main.tf":                                "resource {}",
+		"modules/vpc/main.tf":                    "resource {}",
+		".terraform/modules/modules.json":        "{}",
+		".terraform/providers/provider":          "binary",
+		".git/HEAD":                              "ref: refs/heads/main",
+		"terraform.tfstate":                      "{}",
+		"crossplane-provider-terraform-0.tfvars": "a = 1",
+		".crossplane-module-revision":            "git::https://example.org/module.git@cafe",
+	}
+
+	cases := map[string]struct {
+		reason  string
+		ignore  []string
+		changed map[string]string
+		same    bool
+	}{
+		"IgnoredFileChanged": {
+			reason:  "Changes to files that are ignored by default should not change the checksum.",
+			changed: map[string]string{".terraform/providers/provider": "new", "terraform.tfstate": "new", "crossplane-provider-terraform-0.tfvars": "a = 2", ".git/ORIG_HEAD": "new"},
+			same:    true,
+		},
+		"ConfiguredIgnoredFileChanged": {
+			reason:  "Changes to files that match the supplied patterns should not change the checksum.",
+			ignore:  []string{".crossplane-module-revision"},
+			changed: map[string]string{".crossplane-module-revision": "git::https://example.org/module.git@beef"},
+			same:    true,
+		},
+		"FileChanged": {
+			reason:  "Changes to files that are not ignored should change the checksum.",
+			changed: map[string]string{"modules/vpc/main.tf": "resource { new = true }"},
+			same:    false,
+		},
+		"FileAdded": {
+			reason:  "Adding files that are not ignored should change the checksum.",
+			changed: map[string]string{".terraform/modules/vpc/main.tf": "resource {}"},
+			same:    false,
+		},
+	}
+
+	write := func(t *testing.T, dir string, files map[string]string) {
+		t.Helper()
+		for p, data := range files {
+			if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, p), []byte(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			write(t, dir, base)
+			h := Harness{Dir: dir, ChecksumIgnore: tc.ignore}
+
+			before, err := h.GenerateChecksum(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			write(t, dir, tc.changed)
+			after, err := h.GenerateChecksum(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.same, before == after); diff != "" {
+				t.Errorf("\n%s\nh.GenerateChecksum(...): -want same checksum, +got same checksum:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+type runnerFn func(ctx context.Context, cmd *exec.Cmd) ([]byte, error)
+
+func (fn runnerFn) Run(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	return fn(ctx, cmd)
+}
+
+type limiterFn func(ctx context.Context) (func(), error)
+
+func (fn limiterFn) Acquire(ctx context.Context) (func(), error) {
+	return fn(ctx)
+}
+
+func TestDiffRunner(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type want struct {
+		differs bool
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		limiter Limiter
+		out     []byte
+		err     error
+		want    want
+	}{
+		"NoDiff": {
+			reason: "A plan that exits successfully has no diff.",
+			want:   want{differs: false},
+		},
+		"Diff": {
+			reason: "A plan that exits with exit code 2 has a diff.",
+			err:    &ExitError{Code: 2},
+			want:   want{differs: true},
+		},
+		"RunError": {
+			reason: "Errors running the plan should be returned.",
+			err:    errBoom,
+			want:   want{err: errBoom},
+		},
+		"AcquireError": {
+			reason: "Errors waiting for the limiter to allow the plan to run should be returned.",
+			limiter: limiterFn(func(_ context.Context) (func(), error) {
+				return nil, errBoom
+			}),
+			want: want{err: errors.Wrap(errBoom, errAcquire)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := Harness{Path: "terraform", Dir: t.TempDir(), Limiter: tc.limiter, Runner: runnerFn(func(_ context.Context, _ *exec.Cmd) ([]byte, error) {
+				return tc.out, tc.err
+			})}
+			differs, err := h.Diff(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nh.Diff(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.differs, differs); diff != "" {
+				t.Errorf("\n%s\nh.Diff(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
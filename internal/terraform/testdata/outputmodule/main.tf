@@ -17,6 +17,12 @@ output "object" {
   }
 }
 
+output "map" {
+  value = tomap({
+    such : "map"
+  })
+}
+
 output "bool" {
   value = true
 }
@@ -0,0 +1,167 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package verify verifies the content of Terraform modules.
+package verify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// Error strings.
+const (
+	errWalk             = "cannot walk module directory"
+	errHash             = "cannot hash module file"
+	errFmtDigest        = "module digest %s does not match expected digest %s"
+	errNoPublicKeys     = "no PEM encoded public keys were supplied"
+	errParsePublicKey   = "cannot parse PEM encoded public key"
+	errFmtKeyType       = "unsupported public key type %T"
+	errInvalidSignature = "module signature is not valid for any of the supplied public keys"
+)
+
+// DigestAlgorithm is the prefix of a module digest.
+const DigestAlgorithm = "sha256:"
+
+// Manifest returns a manifest of the files in the supplied directory, in the
+// format produced by sha256sum. Each line contains the hex encoded SHA256 hash
+// of a file's content and its path relative to the directory, separated by
+// two spaces. Lines are sorted by path. Directories named .git are skipped.
+func Manifest(fs afero.Afero, dir string) ([]byte, error) {
+	paths := []string{}
+	err := fs.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, errWalk)
+	}
+	sort.Strings(paths)
+
+	m := &bytes.Buffer{}
+	for _, p := range paths {
+		h, err := hashFile(fs, filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, errors.Wrap(err, errHash)
+		}
+		fmt.Fprintf(m, "%x  %s\n", h, p)
+	}
+	return m.Bytes(), nil
+}
+
+func hashFile(fs afero.Afero, path string) ([]byte, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck // Only reads from f.
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Digest returns the digest of the supplied manifest, in the form
+// sha256:<hex>.
+func Digest(manifest []byte) string {
+	h := sha256.Sum256(manifest)
+	return DigestAlgorithm + hex.EncodeToString(h[:])
+}
+
+// VerifyDigest returns an error if the digest of the supplied manifest does
+// not match the supplied digest.
+func VerifyDigest(manifest []byte, digest string) error {
+	if d := Digest(manifest); d != digest {
+		return errors.Errorf(errFmtDigest, d, digest)
+	}
+	return nil
+}
+
+// VerifySignature returns an error unless the supplied detached signature of
+// the supplied manifest is valid for at least one of the supplied PEM encoded
+// public keys. ECDSA and RSA (PKCS #1 v1.5) signatures must be of the SHA256
+// hash of the manifest, while Ed25519 signatures must be of the manifest
+// itself.
+func VerifySignature(manifest, sig []byte, pems ...[]byte) error {
+	keys := []crypto.PublicKey{}
+	for _, rest := range pems {
+		for {
+			var b *pem.Block
+			b, rest = pem.Decode(rest)
+			if b == nil {
+				break
+			}
+			k, err := x509.ParsePKIXPublicKey(b.Bytes)
+			if err != nil {
+				return errors.Wrap(err, errParsePublicKey)
+			}
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New(errNoPublicKeys)
+	}
+
+	h := sha256.Sum256(manifest)
+	for _, k := range keys {
+		switch k := k.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, h[:], sig) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, manifest, sig) {
+				return nil
+			}
+		default:
+			return errors.Errorf(errFmtKeyType, k)
+		}
+	}
+	return errors.New(errInvalidSignature)
+}
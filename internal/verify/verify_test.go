@@ -0,0 +1,176 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+)
+
+func TestManifest(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	files := map[string]string{
+		"/tf/ws/main.tf":                 "main",
+		"/tf/ws/modules/network/main.tf": "network",
+		"/tf/ws/.git/HEAD":               "ref: refs/heads/main",
+	}
+	for p, c := range files {
+		if err := fs.WriteFile(p, []byte(c), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Per printf main | sha256sum, and printf network | sha256sum.
+	want := "" +
+		"0d6e4079e36703ebd37c00722f5891d28b0e2811dc114b129215123adcce3605  main.tf\n" +
+		"3009be769fb8f956e8413ee9f3e0836e34968bc40457d0a10c549d2edcf00cc1  modules/network/main.tf\n"
+
+	got, err := Manifest(fs, "/tf/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Manifest(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	manifest := []byte("0d6e4079e36703ebd37c00722f5891d28b0e2811dc114b129215123adcce3605  main.tf\n")
+	digest := Digest(manifest)
+
+	cases := map[string]struct {
+		digest string
+		want   error
+	}{
+		"Match": {
+			digest: digest,
+		},
+		"Mismatch": {
+			digest: "sha256:00",
+			want:   errors.Errorf(errFmtDigest, digest, "sha256:00"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := VerifyDigest(manifest, tc.digest)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("VerifyDigest(...): -want error, +got error:\n%s", diff)
+			}
+		})
+	}
+}
+
+func publicKeyPEM(t *testing.T, k crypto.PublicKey) []byte {
+	t.Helper()
+	b, err := x509.MarshalPKIXPublicKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b})
+}
+
+func TestVerifySignature(t *testing.T) {
+	manifest := []byte("0d6e4079e36703ebd37c00722f5891d28b0e2811dc114b129215123adcce3605  main.tf\n")
+	h := sha256.Sum256(manifest)
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ec, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSig, err := rsa.SignPKCS1v15(rand.Reader, r, crypto.SHA256, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPub, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSig := ed25519.Sign(ed, manifest)
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		sig  []byte
+		pems [][]byte
+	}
+	cases := map[string]struct {
+		args args
+		want error
+	}{
+		"ECDSA": {
+			args: args{sig: ecSig, pems: [][]byte{publicKeyPEM(t, &ec.PublicKey)}},
+		},
+		"RSA": {
+			args: args{sig: rsaSig, pems: [][]byte{publicKeyPEM(t, &r.PublicKey)}},
+		},
+		"Ed25519": {
+			args: args{sig: edSig, pems: [][]byte{publicKeyPEM(t, edPub)}},
+		},
+		"AnyKey": {
+			args: args{sig: ecSig, pems: [][]byte{append(publicKeyPEM(t, &other.PublicKey), publicKeyPEM(t, &ec.PublicKey)...)}},
+		},
+		"WrongKey": {
+			args: args{sig: ecSig, pems: [][]byte{publicKeyPEM(t, &other.PublicKey)}},
+			want: errors.New(errInvalidSignature),
+		},
+		"TamperedSignature": {
+			args: args{sig: edSig[1:], pems: [][]byte{publicKeyPEM(t, edPub)}},
+			want: errors.New(errInvalidSignature),
+		},
+		"NoKeys": {
+			args: args{sig: ecSig, pems: [][]byte{[]byte("I'm not PEM!")}},
+			want: errors.New(errNoPublicKeys),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := VerifySignature(manifest, tc.args.sig, tc.args.pems...)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("VerifySignature(...): -want error, +got error:\n%s", diff)
+			}
+		})
+	}
+}
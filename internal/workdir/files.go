@@ -0,0 +1,334 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workdir
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	errFmtUnsafePath = "refusing to write %q outside of the working directory"
+	errFmtNotAFile   = "refusing to write %q over the working directory"
+	errFmtExtract    = "cannot extract %q"
+	errReadManifest  = "cannot read manifest of module files"
+	errWriteManifest = "cannot write manifest of module files"
+	errFmtPrune      = "cannot remove module file %q"
+	errFmtUnsafeLink = "refusing to copy symlink %q to %q, which is outside of the working directory"
+	errNoSymlinks    = "filesystem does not support symlinks"
+)
+
+// ManifestFile records the files that were written to a working directory
+// from a module source, relative to the working directory.
+const ManifestFile = ".crossplane-module-files"
+
+// Untar extracts the supplied gzipped tarball into the supplied directory,
+// returning the paths of the extracted files relative to the directory. Only
+// regular files and directories are extracted. Files keep the permissions
+// recorded in the tarball, except that they're always readable and writable
+// by their owner, and are never writable by anyone else. Entries that would be
+// extracted outside of the directory are rejected.
+func Untar(fs afero.Afero, r io.Reader, dir string) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close() //nolint:errcheck // Only reads from gz.
+
+	var files []string
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := SafeJoin(dir, h.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := fs.MkdirAll(p, 0700); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := fs.MkdirAll(filepath.Dir(p), 0700); err != nil {
+				return nil, err
+			}
+			if err := removeSymlink(fs, p); err != nil {
+				return nil, err
+			}
+			mode := fileMode(h.FileInfo().Mode())
+			f, err := fs.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(f, tr) //nolint:gosec // Modules are trusted not to be decompression bombs.
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err == nil {
+				// The mode isn't changed if the file already existed.
+				err = fs.Chmod(p, mode)
+			}
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, filepath.Clean(h.Name))
+		}
+	}
+}
+
+// Prune removes the files that an earlier reconcile wrote to the supplied
+// directory from a module source, but that aren't among the supplied files,
+// which were written from the module source by this reconcile. This ensures
+// files that were removed from the module source are removed from the working
+// directory too. The supplied files are recorded in the ManifestFile, for the
+// next reconcile to prune.
+func Prune(fs afero.Afero, dir string, files []string) error {
+	keep := make(map[string]bool, len(files))
+	for _, f := range files {
+		keep[filepath.Clean(f)] = true
+	}
+
+	b, err := fs.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, errReadManifest)
+	}
+	for _, f := range strings.Split(string(b), "\n") {
+		if f == "" || keep[f] {
+			continue
+		}
+		// The manifest is only ever written by us, but we don't trust it to
+		// point into the working directory.
+		p, err := SafeJoin(dir, f)
+		if err != nil {
+			return err
+		}
+		if err := fs.Remove(p); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, errFmtPrune, f)
+		}
+	}
+
+	written := make([]string, 0, len(keep))
+	for f := range keep {
+		written = append(written, f)
+	}
+	sort.Strings(written)
+	return errors.Wrap(fs.WriteFile(filepath.Join(dir, ManifestFile), []byte(strings.Join(written, "\n")), 0600), errWriteManifest)
+}
+
+// WriteFiles writes the supplied files, keyed by their path relative to the
+// supplied directory, creating any parent directories as needed. It returns
+// the paths of the written files relative to the directory. Files that would
+// be written outside of the directory are rejected.
+func WriteFiles(fs afero.Afero, dir string, files map[string]string) ([]string, error) {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	written := make([]string, 0, len(paths))
+	for _, path := range paths {
+		p, err := SafeJoin(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		if p == filepath.Clean(dir) {
+			return nil, errors.Errorf(errFmtNotAFile, path)
+		}
+		if err := fs.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			return nil, err
+		}
+		if err := fs.WriteFile(p, []byte(files[path]), 0600); err != nil {
+			return nil, err
+		}
+		written = append(written, filepath.Clean(path))
+	}
+	return written, nil
+}
+
+// WriteConfigMap writes the supplied ConfigMap to the supplied directory,
+// returning the paths of the written files relative to the directory. Each key
+// of its data is written to a file of that name. Each key of its binary data
+// that ends in .tar.gz or .tgz is treated as a gzipped tarball and extracted,
+// while any other key is written to a file of that name.
+func WriteConfigMap(fs afero.Afero, dir string, cm *corev1.ConfigMap) ([]string, error) {
+	written, err := WriteFiles(fs, dir, cm.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(cm.BinaryData))
+	for k := range cm.BinaryData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !strings.HasSuffix(k, ".tar.gz") && !strings.HasSuffix(k, ".tgz") {
+			files, err := WriteFiles(fs, dir, map[string]string{k: string(cm.BinaryData[k])})
+			if err != nil {
+				return nil, err
+			}
+			written = append(written, files...)
+			continue
+		}
+		files, err := Untar(fs, bytes.NewReader(cm.BinaryData[k]), dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtExtract, k)
+		}
+		written = append(written, files...)
+	}
+	return written, nil
+}
+
+// Copy the files in the supplied source directory to the supplied destination
+// directory, creating any directories as needed. Existing files are
+// overwritten. Files keep their permissions, except that they're always
+// readable and writable by their owner, and are never writable by anyone
+// else. Symlinks are copied as symlinks, as long as they point within the
+// destination directory. It returns the paths of the copied files and
+// symlinks relative to the destination directory.
+func Copy(fs afero.Afero, src, dst string) ([]string, error) {
+	var copied []string
+	err := fs.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		p := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return fs.MkdirAll(p, 0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			if err := copySymlink(fs, path, dst, rel); err != nil {
+				return err
+			}
+			copied = append(copied, rel)
+			return nil
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		// Don't write through a symlink an earlier copy left behind.
+		if err := removeSymlink(fs, p); err != nil {
+			return err
+		}
+		in, err := fs.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close() //nolint:errcheck // Only reads from in.
+		mode := fileMode(info.Mode())
+		out, err := fs.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			// The mode isn't changed if the file already existed.
+			err = fs.Chmod(p, mode)
+		}
+		copied = append(copied, rel)
+		return err
+	})
+	return copied, err
+}
+
+// copySymlink copies the supplied symlink to the supplied path relative to the
+// supplied destination directory, replacing any file already there. It returns
+// an error if the symlink points outside of the destination directory.
+func copySymlink(fs afero.Afero, path, dst, rel string) error {
+	lr, ok := fs.Fs.(afero.LinkReader)
+	l, lok := fs.Fs.(afero.Linker)
+	if !ok || !lok {
+		return errors.New(errNoSymlinks)
+	}
+	target, err := lr.ReadlinkIfPossible(path)
+	if err != nil {
+		return err
+	}
+	if filepath.IsAbs(target) {
+		return errors.Errorf(errFmtUnsafeLink, rel, target)
+	}
+	if _, err := SafeJoin(dst, filepath.Join(filepath.Dir(rel), target)); err != nil {
+		return errors.Errorf(errFmtUnsafeLink, rel, target)
+	}
+	p := filepath.Join(dst, rel)
+	if err := fs.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return l.SymlinkIfPossible(target, p)
+}
+
+// removeSymlink removes the supplied path if it is a symlink.
+func removeSymlink(fs afero.Afero, p string) error {
+	ls, ok := fs.Fs.(afero.Lstater)
+	if !ok {
+		return nil
+	}
+	fi, _, err := ls.LstatIfPossible(p)
+	if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		return nil //nolint:nilerr // There's nothing to remove.
+	}
+	return fs.Remove(p)
+}
+
+// fileMode returns the mode with which to write a file from a module whose
+// mode is the supplied mode. Files keep their permission bits, such as
+// whether they're executable, but are always readable and writable by their
+// owner, and never writable by anyone else. Special bits such as setuid are
+// dropped.
+func fileMode(m os.FileMode) os.FileMode {
+	return m.Perm()&0o755 | 0o600
+}
+
+// SafeJoin joins the supplied relative path to the supplied directory,
+// returning an error if the result would be outside of the directory.
+func SafeJoin(dir, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", errors.Errorf(errFmtUnsafePath, path)
+	}
+	p := filepath.Join(dir, path)
+	rel, err := filepath.Rel(dir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf(errFmtUnsafePath, path)
+	}
+	return p, nil
+}
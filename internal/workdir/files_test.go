@@ -0,0 +1,499 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workdir
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+)
+
+func TestSafeJoin(t *testing.T) {
+	type want struct {
+		path string
+		err  error
+	}
+	cases := map[string]struct {
+		path string
+		want want
+	}{
+		"File": {
+			path: "main.tf",
+			want: want{path: "/tf/ws/main.tf"},
+		},
+		"NestedFile": {
+			path: "modules/a/../b/main.tf",
+			want: want{path: "/tf/ws/modules/b/main.tf"},
+		},
+		"Absolute": {
+			path: "/etc/passwd",
+			want: want{err: errors.Errorf(errFmtUnsafePath, "/etc/passwd")},
+		},
+		"Traversal": {
+			path: "../other/main.tf",
+			want: want{err: errors.Errorf(errFmtUnsafePath, "../other/main.tf")},
+		},
+		"Parent": {
+			path: "modules/../..",
+			want: want{err: errors.Errorf(errFmtUnsafePath, "modules/../..")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := SafeJoin("/tf/ws", tc.path)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("SafeJoin(...): -want error, +got error:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want.path, got); diff != "" {
+				t.Errorf("SafeJoin(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	type want struct {
+		files   map[string]string
+		written []string
+		err     error
+	}
+	cases := map[string]struct {
+		files map[string]string
+		want  want
+	}{
+		"Files": {
+			files: map[string]string{
+				"main.tf":                   "main",
+				"variables.tf":              "variables",
+				"modules/network/main.tf":   "network",
+				"templates/user-data.tftpl": "#!/bin/sh",
+			},
+			want: want{
+				files: map[string]string{
+					"/tf/ws/main.tf":                   "main",
+					"/tf/ws/variables.tf":              "variables",
+					"/tf/ws/modules/network/main.tf":   "network",
+					"/tf/ws/templates/user-data.tftpl": "#!/bin/sh",
+				},
+				written: []string{"main.tf", "modules/network/main.tf", "templates/user-data.tftpl", "variables.tf"},
+			},
+		},
+		"Traversal": {
+			files: map[string]string{
+				"../other/main.tf": "nope",
+			},
+			want: want{
+				files: map[string]string{},
+				err:   errors.Errorf(errFmtUnsafePath, "../other/main.tf"),
+			},
+		},
+		"WorkingDirectory": {
+			files: map[string]string{
+				".": "nope",
+			},
+			want: want{
+				files: map[string]string{},
+				err:   errors.Errorf(errFmtNotAFile, "."),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.Afero{Fs: afero.NewMemMapFs()}
+			written, err := WriteFiles(fs, "/tf/ws", tc.files)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("WriteFiles(...): -want error, +got error:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want.written, written); diff != "" {
+				t.Errorf("WriteFiles(...): -want written, +got written:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want.files, readFiles(t, fs)); diff != "" {
+				t.Errorf("WriteFiles(...): -want files, +got files:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUntarModes(t *testing.T) {
+	modes := map[string]int64{
+		"main.tf":         0o644,
+		"scripts/run.sh":  0o755,
+		"scripts/open.sh": 0o777 | 0o4000,
+		"secret.txt":      0o400,
+	}
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, mode := range modes {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(name)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	// A file extracted by an earlier reconcile, before it was executable.
+	if err := fs.WriteFile("/tf/ws/scripts/run.sh", []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Untar(fs, buf, "/tf/ws"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]os.FileMode{
+		"main.tf":         0o644,
+		"scripts/run.sh":  0o755,
+		"scripts/open.sh": 0o755,
+		"secret.txt":      0o600,
+	}
+	for p, m := range want {
+		fi, err := fs.Stat(filepath.Join("/tf/ws", p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(m, fi.Mode()); diff != "" {
+			t.Errorf("Untar(...): %s: -want mode, +got mode:\n%s", p, diff)
+		}
+	}
+}
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readFiles(t *testing.T, fs afero.Afero) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := fs.Walk("/", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(path)
+		files[path] = string(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestWriteConfigMap(t *testing.T) {
+	type want struct {
+		files   map[string]string
+		written []string
+		err     error
+	}
+	cases := map[string]struct {
+		cm   func(t *testing.T) *corev1.ConfigMap
+		want want
+	}{
+		"DataAndBinaryData": {
+			cm: func(_ *testing.T) *corev1.ConfigMap {
+				return &corev1.ConfigMap{
+					Data:       map[string]string{"main.tf": "main"},
+					BinaryData: map[string][]byte{"logo.png": []byte("png")},
+				}
+			},
+			want: want{
+				files: map[string]string{
+					"/tf/ws/main.tf":  "main",
+					"/tf/ws/logo.png": "png",
+				},
+				written: []string{"main.tf", "logo.png"},
+			},
+		},
+		"Tarball": {
+			cm: func(t *testing.T) *corev1.ConfigMap {
+				return &corev1.ConfigMap{
+					Data:       map[string]string{"main.tf": "main"},
+					BinaryData: map[string][]byte{"modules.tgz": tarball(t, map[string]string{"modules/network/main.tf": "network"})},
+				}
+			},
+			want: want{
+				files: map[string]string{
+					"/tf/ws/main.tf":                 "main",
+					"/tf/ws/modules/network/main.tf": "network",
+				},
+				written: []string{"main.tf", "modules/network/main.tf"},
+			},
+		},
+		"NotATarball": {
+			cm: func(_ *testing.T) *corev1.ConfigMap {
+				return &corev1.ConfigMap{
+					BinaryData: map[string][]byte{"module.tar.gz": []byte("nope")},
+				}
+			},
+			want: want{
+				files: map[string]string{},
+				err:   errors.Wrapf(io.ErrUnexpectedEOF, errFmtExtract, "module.tar.gz"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.Afero{Fs: afero.NewMemMapFs()}
+			written, err := WriteConfigMap(fs, "/tf/ws", tc.cm(t))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("WriteConfigMap(...): -want error, +got error:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want.written, written); diff != "" {
+				t.Errorf("WriteConfigMap(...): -want written, +got written:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want.files, readFiles(t, fs)); diff != "" {
+				t.Errorf("WriteConfigMap(...): -want files, +got files:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCopy(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	for p, c := range map[string]string{
+		"/tmp/tf/ws/module/main.tf":                 "main",
+		"/tmp/tf/ws/module/modules/network/main.tf": "network",
+		"/tf/ws/main.tf":                            "stale",
+		"/tf/ws/.terraform.lock.hcl":                "lock",
+	} {
+		if err := fs.WriteFile(p, []byte(c), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	copied, err := Copy(fs, "/tmp/tf/ws/module", "/tf/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"main.tf", "modules/network/main.tf"}, copied); diff != "" {
+		t.Errorf("Copy(...): -want copied, +got copied:\n%s", diff)
+	}
+
+	want := map[string]string{
+		"/tmp/tf/ws/module/main.tf":                 "main",
+		"/tmp/tf/ws/module/modules/network/main.tf": "network",
+		"/tf/ws/main.tf":                            "main",
+		"/tf/ws/modules/network/main.tf":            "network",
+		"/tf/ws/.terraform.lock.hcl":                "lock",
+	}
+	if diff := cmp.Diff(want, readFiles(t, fs)); diff != "" {
+		t.Errorf("Copy(...): -want files, +got files:\n%s", diff)
+	}
+}
+
+func TestCopyModesAndSymlinks(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		links  map[string]string
+		want   map[string]os.FileMode
+		err    error
+	}{
+		"ModesAndSymlinks": {
+			reason: "Files should keep their permissions, less write permission for others, and symlinks within the module should be copied.",
+			links: map[string]string{
+				"modules/shared":  "../shared",
+				"scripts/link.sh": "run.sh",
+			},
+			want: map[string]os.FileMode{
+				"main.tf":         0o644,
+				"scripts/run.sh":  0o755,
+				"scripts/open.sh": 0o755,
+				"shared/main.tf":  0o600,
+				"modules/shared":  os.ModeSymlink,
+				"scripts/link.sh": os.ModeSymlink,
+			},
+		},
+		"UnsafeSymlink": {
+			reason: "Symlinks that point outside of the working directory should be rejected.",
+			links:  map[string]string{"secrets": "../../../etc"},
+			err:    errors.Errorf(errFmtUnsafeLink, "secrets", "../../../etc"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.Afero{Fs: afero.NewOsFs()}
+			src, dst := t.TempDir(), t.TempDir()
+			for p, m := range map[string]os.FileMode{
+				"main.tf":         0o644,
+				"scripts/run.sh":  0o755,
+				"scripts/open.sh": 0o777 | os.ModeSetuid,
+				"shared/main.tf":  0o400,
+			} {
+				if err := fs.MkdirAll(filepath.Join(src, filepath.Dir(p)), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := fs.WriteFile(filepath.Join(src, p), []byte(p), 0600); err != nil {
+					t.Fatal(err)
+				}
+				if err := fs.Chmod(filepath.Join(src, p), m); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for p, target := range tc.links {
+				if err := fs.MkdirAll(filepath.Join(src, filepath.Dir(p)), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(target, filepath.Join(src, p)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// A file copied by an earlier reconcile, before it was executable.
+			if err := fs.MkdirAll(filepath.Join(dst, "scripts"), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := fs.WriteFile(filepath.Join(dst, "scripts/run.sh"), []byte("old"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Copy(fs, src, dst)
+			if diff := cmp.Diff(tc.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nCopy(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			for p, want := range tc.want {
+				fi, err := os.Lstat(filepath.Join(dst, p))
+				if err != nil {
+					t.Errorf("\n%s\nCopy(...): %s", tc.reason, err)
+					continue
+				}
+				got := fi.Mode().Perm()
+				if fi.Mode()&os.ModeSymlink != 0 {
+					got = os.ModeSymlink
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("\n%s\nCopy(...): %s: -want mode, +got mode:\n%s", tc.reason, p, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	type want struct {
+		files map[string]string
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		files  map[string]string
+		keep   []string
+		want   want
+	}{
+		"NoManifest": {
+			reason: "Only the manifest should be written if no files were written by an earlier reconcile.",
+			files:  map[string]string{"/tf/ws/main.tf": "main", "/tf/ws/.terraform.lock.hcl": "lock"},
+			keep:   []string{"main.tf"},
+			want: want{
+				files: map[string]string{
+					"/tf/ws/main.tf":             "main",
+					"/tf/ws/.terraform.lock.hcl": "lock",
+					"/tf/ws/" + ManifestFile:     "main.tf",
+				},
+			},
+		},
+		"RemovedFromSource": {
+			reason: "Files written by an earlier reconcile that weren't written by this one should be removed.",
+			files: map[string]string{
+				"/tf/ws/main.tf":              "main",
+				"/tf/ws/modules/old/main.tf":  "old",
+				"/tf/ws/.terraform.lock.hcl":  "lock",
+				"/tf/ws/" + ManifestFile:      "main.tf\nmodules/old/main.tf\ngone.tf",
+				"/tf/ws/crossplane-config.tf": "config",
+			},
+			keep: []string{"main.tf", "variables.tf"},
+			want: want{
+				files: map[string]string{
+					"/tf/ws/main.tf":              "main",
+					"/tf/ws/.terraform.lock.hcl":  "lock",
+					"/tf/ws/" + ManifestFile:      "main.tf\nvariables.tf",
+					"/tf/ws/crossplane-config.tf": "config",
+				},
+			},
+		},
+		"Traversal": {
+			reason: "Files outside of the working directory should never be removed.",
+			files: map[string]string{
+				"/tf/other/main.tf":      "other",
+				"/tf/ws/" + ManifestFile: "../other/main.tf",
+			},
+			want: want{
+				files: map[string]string{
+					"/tf/other/main.tf":      "other",
+					"/tf/ws/" + ManifestFile: "../other/main.tf",
+				},
+				err: errors.Errorf(errFmtUnsafePath, "../other/main.tf"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.Afero{Fs: afero.NewMemMapFs()}
+			for p, c := range tc.files {
+				if err := fs.WriteFile(p, []byte(c), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := Prune(fs, "/tf/ws", tc.keep)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPrune(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.files, readFiles(t, fs)); diff != "" {
+				t.Errorf("\n%s\nPrune(...): -want files, +got files:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
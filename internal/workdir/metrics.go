@@ -0,0 +1,129 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workdir
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const subSystem = "provider_terraform"
+
+// Metrics records the disk usage of working directories, and what the
+// GarbageCollector did, so that disk pressure caused by working directories
+// that aren't garbage collected can be told apart from that caused by
+// Workspaces that still exist.
+type Metrics struct {
+	usage   *prometheus.GaugeVec
+	scanned *prometheus.CounterVec
+	removed *prometheus.CounterVec
+	failed  *prometheus.CounterVec
+	freed   *prometheus.CounterVec
+}
+
+// NewMetrics returns a new Metrics which records working directory metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		usage: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Subsystem: subSystem,
+			Name:      "workspace_disk_usage_bytes",
+			Help:      "The size in bytes of the working directories of a Workspace",
+		}, []string{"gvk", "namespace", "name"}),
+		scanned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subSystem,
+			Name:      "workdir_gc_scanned_total",
+			Help:      "The number of working directories the garbage collector scanned",
+		}, []string{"dir"}),
+		removed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subSystem,
+			Name:      "workdir_gc_removed_total",
+			Help:      "The number of working directories the garbage collector removed",
+		}, []string{"dir"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subSystem,
+			Name:      "workdir_gc_failed_total",
+			Help:      "The number of working directories the garbage collector failed to remove",
+		}, []string{"dir"}),
+		freed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subSystem,
+			Name:      "workdir_gc_freed_bytes_total",
+			Help:      "The size in bytes of the working directories the garbage collector removed",
+		}, []string{"dir"}),
+	}
+}
+
+// Describe sends the super-set of all possible descriptors of metrics
+// collected by this Collector to the provided channel and returns once
+// the last descriptor has been sent.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.usage.Describe(ch)
+	m.scanned.Describe(ch)
+	m.removed.Describe(ch)
+	m.failed.Describe(ch)
+	m.freed.Describe(ch)
+}
+
+// Collect is called by the Prometheus registry when collecting
+// metrics. The implementation sends each collected metric via the
+// provided channel and returns once the last metric has been sent.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.usage.Collect(ch)
+	m.scanned.Collect(ch)
+	m.removed.Collect(ch)
+	m.failed.Collect(ch)
+	m.freed.Collect(ch)
+}
+
+// RecordUsage records the disk usage of the working directories of the
+// supplied Workspace.
+func (m *Metrics) RecordUsage(gvk schema.GroupVersionKind, namespace, name string, bytes int64) {
+	if m == nil {
+		return
+	}
+	m.usage.WithLabelValues(gvk.String(), namespace, name).Set(float64(bytes))
+}
+
+// DeleteUsage stops recording the disk usage of the working directories of
+// the supplied Workspace, once they've been removed.
+func (m *Metrics) DeleteUsage(gvk schema.GroupVersionKind, namespace, name string) {
+	if m == nil {
+		return
+	}
+	m.usage.DeleteLabelValues(gvk.String(), namespace, name)
+}
+
+func (m *Metrics) recordScanned(dir string) {
+	if m == nil {
+		return
+	}
+	m.scanned.WithLabelValues(dir).Inc()
+}
+
+func (m *Metrics) recordRemoved(dir string, bytes int64) {
+	if m == nil {
+		return
+	}
+	m.removed.WithLabelValues(dir).Inc()
+	m.freed.WithLabelValues(dir).Add(float64(bytes))
+}
+
+func (m *Metrics) recordFailed(dir string) {
+	if m == nil {
+		return
+	}
+	m.failed.WithLabelValues(dir).Inc()
+}
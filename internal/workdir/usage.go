@@ -0,0 +1,142 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workdir
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// A DiskUsage periodically measures the disk usage of the working directories
+// of Workspaces, so that it needn't be measured each time a Workspace is
+// reconciled.
+type DiskUsage struct {
+	fs         afero.Afero
+	parentDirs []string
+	interval   time.Duration
+	log        logging.Logger
+
+	mu    sync.RWMutex
+	usage map[string]int64
+}
+
+// A DiskUsageOption configures a new DiskUsage.
+type DiskUsageOption func(*DiskUsage)
+
+// WithUsageFs configures the afero filesystem implementation in which the
+// disk usage of working directories is measured. The default is the real
+// operating system filesystem.
+func WithUsageFs(fs afero.Afero) DiskUsageOption {
+	return func(u *DiskUsage) { u.fs = fs }
+}
+
+// WithUsageInterval configures how often disk usage is measured. The default
+// interval is one minute.
+func WithUsageInterval(i time.Duration) DiskUsageOption {
+	return func(u *DiskUsage) { u.interval = i }
+}
+
+// WithUsageLogger configures the logger that will be used. The default is a
+// no-op logger that never emits logs.
+func WithUsageLogger(l logging.Logger) DiskUsageOption {
+	return func(u *DiskUsage) { u.log = l }
+}
+
+// NewDiskUsage returns a DiskUsage that measures the working directories in
+// the supplied parent directories. The working directories of a Workspace in
+// each parent directory are named for its UID.
+func NewDiskUsage(parentDirs []string, o ...DiskUsageOption) *DiskUsage {
+	u := &DiskUsage{
+		fs:         afero.Afero{Fs: afero.NewOsFs()},
+		parentDirs: parentDirs,
+		interval:   1 * time.Minute,
+		log:        logging.NewNopLogger(),
+		usage:      map[string]int64{},
+	}
+
+	for _, fn := range o {
+		fn(u)
+	}
+
+	return u
+}
+
+// Run measures disk usage periodically. Blocks until the supplied context is
+// done.
+func (u *DiskUsage) Run(ctx context.Context) error {
+	t := time.NewTicker(u.interval)
+	defer t.Stop()
+	for {
+		if err := u.Measure(); err != nil {
+			u.log.Info("Cannot measure working directory disk usage", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Measure the disk usage of the working directories of all Workspaces.
+func (u *DiskUsage) Measure() error {
+	usage := map[string]int64{}
+	for _, dir := range u.parentDirs {
+		fis, err := u.fs.ReadDir(dir)
+		if resource.Ignore(os.IsNotExist, err) != nil {
+			return errors.Wrapf(err, errFmtReadDir, dir)
+		}
+		for _, fi := range fis {
+			if !fi.IsDir() || !isUUID(fi.Name()) {
+				continue
+			}
+			s, err := Usage(u.fs, filepath.Join(dir, fi.Name()))
+			if err != nil {
+				// The directory may have been removed while we measured it.
+				u.log.Debug("Cannot measure working directory disk usage", "error", err)
+				continue
+			}
+			usage[fi.Name()] += s
+		}
+	}
+
+	u.mu.Lock()
+	u.usage = usage
+	u.mu.Unlock()
+	return nil
+}
+
+// Get the disk usage of the working directories of the Workspace with the
+// supplied UID, as of when it was last measured. It returns false if they
+// weren't measured.
+func (u *DiskUsage) Get(uid string) (int64, bool) {
+	if u == nil {
+		return 0, false
+	}
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	s, ok := u.usage[uid]
+	return s, ok
+}
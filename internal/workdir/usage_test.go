@@ -0,0 +1,78 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workdir
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+func TestDiskUsage(t *testing.T) {
+	const (
+		uid   = "0b1c2d3e-4f50-6172-8394-a5b6c7d8e9f0"
+		other = "1b1c2d3e-4f50-6172-8394-a5b6c7d8e9f0"
+	)
+
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	for p, c := range map[string]string{
+		"/tf/" + uid + "/main.tf":          "main",
+		"/tmp/tf/" + uid + "/.git-creds":   "creds",
+		"/tf/" + other + "/main.tf":        "other",
+		"/tf/not-a-workspace/main.tf":      "ignored",
+		"/tf/.modules/modules/abc/main.tf": "ignored",
+	} {
+		if err := fs.WriteFile(p, []byte(c), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u := NewDiskUsage([]string{"/tf", "/tmp/tf", "/does-not-exist"}, WithUsageFs(fs))
+	if _, ok := u.Get(uid); ok {
+		t.Errorf("u.Get(...): want no disk usage before it is measured")
+	}
+	if err := u.Measure(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]int64{}
+	for _, id := range []string{uid, other, "not-a-workspace"} {
+		if s, ok := u.Get(id); ok {
+			got[id] = s
+		}
+	}
+	want := map[string]int64{uid: 9, other: 5}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("u.Measure(): -want usage, +got usage:\n%s", diff)
+	}
+
+	if err := fs.RemoveAll("/tf/" + other); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Measure(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := u.Get(other); ok {
+		t.Errorf("u.Get(...): want no disk usage for a working directory that was removed")
+	}
+
+	var nilUsage *DiskUsage
+	if _, ok := nilUsage.Get(uid); ok {
+		t.Errorf("nil.Get(...): want no disk usage")
+	}
+}
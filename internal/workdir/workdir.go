@@ -18,14 +18,18 @@ package workdir
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1beta1 "github.com/upbound/provider-terraform/apis/cluster/v1beta1"
@@ -36,16 +40,21 @@ import (
 const (
 	errListWorkspaces = "cannot list workspaces"
 	errFmtReadDir     = "cannot read directory %q"
+	errFmtRemoveDirs  = "could not delete directories: %v"
+	errFmtUsage       = "cannot determine disk usage of directory %q"
 )
 
 // A GarbageCollector garbage collects the working directories of Terraform
 // workspaces that no longer exist.
 type GarbageCollector struct {
-	kube      client.Client
+	kube      client.Reader
 	parentDir string
 	fs        afero.Afero
 	interval  time.Duration
 	log       logging.Logger
+	metrics   *Metrics
+	dryRun    bool
+	keep      func(o metav1.Object) bool
 }
 
 // A GarbageCollectorOption configures a new GarbageCollector.
@@ -69,15 +78,35 @@ func WithLogger(l logging.Logger) GarbageCollectorOption {
 	return func(gc *GarbageCollector) { gc.log = l }
 }
 
+// WithDryRun configures the garbage collector to log the working directories
+// it would remove, rather than removing them.
+func WithDryRun(dryRun bool) GarbageCollectorOption {
+	return func(gc *GarbageCollector) { gc.dryRun = dryRun }
+}
+
+// WithKeep configures a function that determines whether the working
+// directory of a workspace that exists is kept. The default is to keep the
+// working directories of all workspaces that exist.
+func WithKeep(fn func(o metav1.Object) bool) GarbageCollectorOption {
+	return func(gc *GarbageCollector) { gc.keep = fn }
+}
+
+// WithMetrics configures the metrics that will be recorded. The default is to
+// record no metrics.
+func WithMetrics(m *Metrics) GarbageCollectorOption {
+	return func(gc *GarbageCollector) { gc.metrics = m }
+}
+
 // NewGarbageCollector returns a garbage collector that garbage collects the
 // working directories of Terraform workspaces.
-func NewGarbageCollector(c client.Client, parentDir string, o ...GarbageCollectorOption) *GarbageCollector {
+func NewGarbageCollector(c client.Reader, parentDir string, o ...GarbageCollectorOption) *GarbageCollector {
 	gc := &GarbageCollector{
 		kube:      c,
 		parentDir: parentDir,
 		fs:        afero.Afero{Fs: afero.NewOsFs()},
 		interval:  1 * time.Hour,
 		log:       logging.NewNopLogger(),
+		keep:      func(_ metav1.Object) bool { return true },
 	}
 
 	for _, fn := range o {
@@ -88,14 +117,15 @@ func NewGarbageCollector(c client.Client, parentDir string, o ...GarbageCollecto
 }
 
 // Run the garbage collector. Blocks until the supplied context is done.
-func (gc *GarbageCollector) Run(ctx context.Context, namespaced bool) {
+func (gc *GarbageCollector) Run(ctx context.Context) error {
 	t := time.NewTicker(gc.interval)
+	defer t.Stop()
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return nil
 		case <-t.C:
-			if err := gc.collect(ctx, namespaced); err != nil {
+			if err := gc.collect(ctx); err != nil {
 				gc.log.Info("Garbage collection failed", "error", err)
 			}
 		}
@@ -107,25 +137,36 @@ func isUUID(u string) bool {
 	return err == nil
 }
 
-func (gc *GarbageCollector) collect(ctx context.Context, namespaced bool) error {
+// exists returns the UIDs of the workspaces that exist, and whether their
+// working directories should be kept. Workspaces of both kinds share a parent
+// directory, so both kinds are listed. A kind whose CRD isn't installed has no
+// workspaces.
+func (gc *GarbageCollector) exists(ctx context.Context) (map[string]bool, error) {
 	exists := map[string]bool{}
 
-	if namespaced {
-		l := &namespacedv1beta1.WorkspaceList{}
-		if err := gc.kube.List(ctx, l); err != nil {
-			return errors.Wrap(err, errListWorkspaces)
-		}
-		for _, ws := range l.Items {
-			exists[string(ws.GetUID())] = true
-		}
-	} else {
-		l := &clusterv1beta1.WorkspaceList{}
-		if err := gc.kube.List(ctx, l); err != nil {
-			return errors.Wrap(err, errListWorkspaces)
-		}
-		for _, ws := range l.Items {
-			exists[string(ws.GetUID())] = true
-		}
+	cl := &clusterv1beta1.WorkspaceList{}
+	if err := gc.kube.List(ctx, cl); resource.Ignore(meta.IsNoMatchError, err) != nil {
+		return nil, errors.Wrap(err, errListWorkspaces)
+	}
+	for i := range cl.Items {
+		exists[string(cl.Items[i].GetUID())] = gc.keep(&cl.Items[i])
+	}
+
+	nl := &namespacedv1beta1.WorkspaceList{}
+	if err := gc.kube.List(ctx, nl); resource.Ignore(meta.IsNoMatchError, err) != nil {
+		return nil, errors.Wrap(err, errListWorkspaces)
+	}
+	for i := range nl.Items {
+		exists[string(nl.Items[i].GetUID())] = gc.keep(&nl.Items[i])
+	}
+
+	return exists, nil
+}
+
+func (gc *GarbageCollector) collect(ctx context.Context) error {
+	exists, err := gc.exists(ctx)
+	if err != nil {
+		return err
 	}
 	fis, err := gc.fs.ReadDir(gc.parentDir)
 	if err != nil {
@@ -137,18 +178,119 @@ func (gc *GarbageCollector) collect(ctx context.Context, namespaced bool) error
 		if !fi.IsDir() || !isUUID(fi.Name()) {
 			continue
 		}
+		gc.metrics.recordScanned(gc.parentDir)
 		if exists[fi.Name()] {
 			continue
 		}
 		path := filepath.Join(gc.parentDir, fi.Name())
-		if err := gc.fs.RemoveAll(path); err != nil {
+		if err := gc.remove(path); err != nil {
 			failed = append(failed, path)
 		}
 	}
 
 	if len(failed) > 0 {
-		return errors.Errorf("could not delete directories: %v", strings.Join(failed, ", "))
+		return errors.Errorf(errFmtRemoveDirs, strings.Join(failed, ", "))
 	}
 
 	return nil
 }
+
+// Restore the working directories of Terraform workspaces that were persisted
+// across provider restarts, for example on a PersistentVolume. Restore must be
+// called before any workspace is reconciled. The working directories of
+// workspaces of either kind that still exist are kept, so that they needn't be
+// fetched and initialized again. Those that can't be used are removed, and
+// will be rebuilt when their workspace is next reconciled. The working
+// directories of workspaces that no longer exist are garbage collected.
+func (gc *GarbageCollector) Restore(ctx context.Context) error {
+	exists, err := gc.exists(ctx)
+	if err != nil {
+		return err
+	}
+
+	fis, err := gc.fs.ReadDir(gc.parentDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, errFmtReadDir, gc.parentDir)
+	}
+
+	restored, removed := 0, 0
+	failed := make([]string, 0)
+	for _, fi := range fis {
+		if !isUUID(fi.Name()) {
+			continue
+		}
+		gc.metrics.recordScanned(gc.parentDir)
+		path := filepath.Join(gc.parentDir, fi.Name())
+		if exists[fi.Name()] && fi.IsDir() && gc.usable(path) {
+			restored++
+			continue
+		}
+		if err := gc.remove(path); err != nil {
+			failed = append(failed, path)
+			continue
+		}
+		removed++
+	}
+	gc.log.Info("Restored workspace directories", "dir", gc.parentDir, "restored", restored, "removed", removed)
+
+	if len(failed) > 0 {
+		return errors.Errorf(errFmtRemoveDirs, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Usage returns the size in bytes of the regular files in the supplied
+// directories. Symlinks aren't followed, so providers installed from the
+// shared plugin cache aren't counted. Directories that don't exist use no
+// space.
+func Usage(fs afero.Afero, dirs ...string) (int64, error) {
+	var size int64
+	for _, dir := range dirs {
+		err := fs.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if os.IsNotExist(err) && path == dir {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if fi.Mode().IsRegular() {
+				size += fi.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, errors.Wrapf(err, errFmtUsage, dir)
+		}
+	}
+	return size, nil
+}
+
+// remove the supplied working directory, recording how much space was freed.
+func (gc *GarbageCollector) remove(path string) error {
+	size, _ := Usage(gc.fs, path)
+	if gc.dryRun {
+		gc.log.Info("Would remove working directory", "dir", path, "bytes", size)
+		return nil
+	}
+	if err := gc.fs.RemoveAll(path); err != nil {
+		gc.metrics.recordFailed(gc.parentDir)
+		return err
+	}
+	gc.metrics.recordRemoved(gc.parentDir, size)
+	return nil
+}
+
+// usable returns true if the supplied working directory can be written to.
+// Directories restored from a volume may not be, for example if the volume
+// was previously mounted with a different owner.
+func (gc *GarbageCollector) usable(dir string) bool {
+	f, err := gc.fs.TempFile(dir, ".restore-")
+	if err != nil {
+		return false
+	}
+	_ = f.Close()
+	return gc.fs.Remove(f.Name()) == nil
+}
@@ -25,7 +25,9 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,6 +35,7 @@ import (
 	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
 
 	"github.com/upbound/provider-terraform/apis/cluster/v1beta1"
+	namespacedv1beta1 "github.com/upbound/provider-terraform/apis/namespaced/v1beta1"
 )
 
 func withDirs(fs afero.Afero, dir ...string) afero.Afero {
@@ -56,11 +59,13 @@ func getDirs(fs afero.Afero, parentDir string) []string {
 
 func TestCollect(t *testing.T) {
 	parentDir := "/test"
+	errBoom := errors.New("boom")
 
 	type fields struct {
 		kube       client.Client
 		parentdDir string
 		fs         afero.Afero
+		dryRun     bool
 	}
 	type args struct {
 		ctx context.Context
@@ -101,10 +106,12 @@ func TestCollect(t *testing.T) {
 			reason: "Workdirs belonging to workspaces that no longer exist should be successfully garbage collected.",
 			fields: fields{
 				kube: &test.MockClient{MockList: test.NewMockListFn(nil, func(obj client.ObjectList) error {
-					*obj.(*v1beta1.WorkspaceList) = v1beta1.WorkspaceList{Items: []v1beta1.Workspace{
-						{ObjectMeta: metav1.ObjectMeta{UID: types.UID("8371dd9e-dd3f-4a42-bd8c-340c4744f6de")}},
-						{ObjectMeta: metav1.ObjectMeta{UID: types.UID("ebaac629-43a3-4b39-8138-d7ac19cafe11")}},
-					}}
+					if l, ok := obj.(*v1beta1.WorkspaceList); ok {
+						l.Items = []v1beta1.Workspace{
+							{ObjectMeta: metav1.ObjectMeta{UID: types.UID("8371dd9e-dd3f-4a42-bd8c-340c4744f6de")}},
+							{ObjectMeta: metav1.ObjectMeta{UID: types.UID("ebaac629-43a3-4b39-8138-d7ac19cafe11")}},
+						}
+					}
 					return nil
 				})},
 				parentdDir: parentDir,
@@ -121,12 +128,88 @@ func TestCollect(t *testing.T) {
 				dirs: []string{"8371dd9e-dd3f-4a42-bd8c-340c4744f6de", "ebaac629-43a3-4b39-8138-d7ac19cafe11", "helm", "registry.terraform.io"},
 			},
 		},
+		"BothKinds": {
+			reason: "Workdirs belonging to namespaced and cluster scoped workspaces should not be garbage collected.",
+			fields: fields{
+				kube: &test.MockClient{MockList: test.NewMockListFn(nil, func(obj client.ObjectList) error {
+					switch l := obj.(type) {
+					case *v1beta1.WorkspaceList:
+						l.Items = []v1beta1.Workspace{{ObjectMeta: metav1.ObjectMeta{UID: types.UID("8371dd9e-dd3f-4a42-bd8c-340c4744f6de")}}}
+					case *namespacedv1beta1.WorkspaceList:
+						l.Items = []namespacedv1beta1.Workspace{{ObjectMeta: metav1.ObjectMeta{UID: types.UID("ebaac629-43a3-4b39-8138-d7ac19cafe11")}}}
+					}
+					return nil
+				})},
+				parentdDir: parentDir,
+				fs: withDirs(afero.Afero{Fs: afero.NewMemMapFs()},
+					parentDir,
+					filepath.Join(parentDir, "8371dd9e-dd3f-4a42-bd8c-340c4744f6de"),
+					filepath.Join(parentDir, "ebaac629-43a3-4b39-8138-d7ac19cafe11"),
+					filepath.Join(parentDir, "0d177133-1a2f-4ce2-93d2-f8212d3344e7"),
+				),
+			},
+			want: want{
+				dirs: []string{"8371dd9e-dd3f-4a42-bd8c-340c4744f6de", "ebaac629-43a3-4b39-8138-d7ac19cafe11"},
+			},
+		},
+		"KindNotInstalled": {
+			reason: "A kind of workspace whose CRD isn't installed should be treated as having no workspaces.",
+			fields: fields{
+				kube: &test.MockClient{MockList: func(_ context.Context, obj client.ObjectList, _ ...client.ListOption) error {
+					if _, ok := obj.(*namespacedv1beta1.WorkspaceList); ok {
+						return &meta.NoKindMatchError{GroupKind: namespacedv1beta1.WorkspaceGroupVersionKind.GroupKind()}
+					}
+					return nil
+				}},
+				parentdDir: parentDir,
+				fs: withDirs(afero.Afero{Fs: afero.NewMemMapFs()},
+					parentDir,
+					filepath.Join(parentDir, "0d177133-1a2f-4ce2-93d2-f8212d3344e7"),
+				),
+			},
+			want: want{},
+		},
+		"ErrListWorkspaces": {
+			reason: "Garbage collection should fail, rather than removing any workdirs, when workspaces cannot be listed.",
+			fields: fields{
+				kube: &test.MockClient{MockList: func(_ context.Context, obj client.ObjectList, _ ...client.ListOption) error {
+					if _, ok := obj.(*namespacedv1beta1.WorkspaceList); ok {
+						return errBoom
+					}
+					return nil
+				}},
+				parentdDir: parentDir,
+				fs: withDirs(afero.Afero{Fs: afero.NewMemMapFs()},
+					parentDir,
+					filepath.Join(parentDir, "0d177133-1a2f-4ce2-93d2-f8212d3344e7"),
+				),
+			},
+			want: want{
+				dirs: []string{"0d177133-1a2f-4ce2-93d2-f8212d3344e7"},
+				err:  errors.Wrap(errBoom, errListWorkspaces),
+			},
+		},
+		"DryRun": {
+			reason: "Workdirs should not be removed in dry-run mode.",
+			fields: fields{
+				kube:       &test.MockClient{MockList: test.NewMockListFn(nil)},
+				parentdDir: parentDir,
+				fs: withDirs(afero.Afero{Fs: afero.NewMemMapFs()},
+					parentDir,
+					filepath.Join(parentDir, "0d177133-1a2f-4ce2-93d2-f8212d3344e7"),
+				),
+				dryRun: true,
+			},
+			want: want{
+				dirs: []string{"0d177133-1a2f-4ce2-93d2-f8212d3344e7"},
+			},
+		},
 	}
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			gc := NewGarbageCollector(tc.fields.kube, tc.fields.parentdDir, WithFs(tc.fields.fs))
-			err := gc.collect(tc.args.ctx, false)
+			gc := NewGarbageCollector(tc.fields.kube, tc.fields.parentdDir, WithFs(tc.fields.fs), WithDryRun(tc.fields.dryRun))
+			err := gc.collect(tc.args.ctx)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("gc.collect(...): -want error, +got error:\n%s", diff)
 			}
@@ -139,3 +222,110 @@ func TestCollect(t *testing.T) {
 	}
 
 }
+
+func TestRestore(t *testing.T) {
+	parentDir := "/test"
+	errBoom := errors.New("boom")
+
+	type fields struct {
+		kube client.Client
+		fs   afero.Afero
+	}
+	type want struct {
+		dirs    []string
+		scanned float64
+		removed float64
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		fields fields
+		want   want
+	}{
+		"ErrListWorkspaces": {
+			reason: "Restore should fail when workspaces cannot be listed, rather than removing their workdirs.",
+			fields: fields{
+				kube: &test.MockClient{MockList: test.NewMockListFn(errBoom)},
+				fs: withDirs(afero.Afero{Fs: afero.NewMemMapFs()},
+					parentDir,
+					filepath.Join(parentDir, "8371dd9e-dd3f-4a42-bd8c-340c4744f6de"),
+				),
+			},
+			want: want{
+				dirs: []string{"8371dd9e-dd3f-4a42-bd8c-340c4744f6de"},
+				err:  errors.Wrap(errBoom, errListWorkspaces),
+			},
+		},
+		"NoParentDir": {
+			reason: "Restore should succeed when no workdirs were persisted.",
+			fields: fields{
+				kube: &test.MockClient{MockList: test.NewMockListFn(nil)},
+				fs:   afero.Afero{Fs: afero.NewMemMapFs()},
+			},
+			want: want{},
+		},
+		"Success": {
+			reason: "Workdirs belonging to workspaces of either kind should be restored, and others garbage collected.",
+			fields: fields{
+				kube: &test.MockClient{MockList: test.NewMockListFn(nil, func(obj client.ObjectList) error {
+					switch l := obj.(type) {
+					case *v1beta1.WorkspaceList:
+						l.Items = []v1beta1.Workspace{{ObjectMeta: metav1.ObjectMeta{UID: types.UID("8371dd9e-dd3f-4a42-bd8c-340c4744f6de")}}}
+					case *namespacedv1beta1.WorkspaceList:
+						l.Items = []namespacedv1beta1.Workspace{{ObjectMeta: metav1.ObjectMeta{UID: types.UID("ebaac629-43a3-4b39-8138-d7ac19cafe11")}}}
+					}
+					return nil
+				})},
+				fs: withDirs(afero.Afero{Fs: afero.NewMemMapFs()},
+					parentDir,
+					filepath.Join(parentDir, "8371dd9e-dd3f-4a42-bd8c-340c4744f6de"),
+					filepath.Join(parentDir, "ebaac629-43a3-4b39-8138-d7ac19cafe11"),
+					filepath.Join(parentDir, "0d177133-1a2f-4ce2-93d2-f8212d3344e7"),
+					filepath.Join(parentDir, "plugin-cache"),
+				),
+			},
+			want: want{
+				dirs:    []string{"8371dd9e-dd3f-4a42-bd8c-340c4744f6de", "ebaac629-43a3-4b39-8138-d7ac19cafe11", "plugin-cache"},
+				scanned: 3,
+				removed: 1,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			m := NewMetrics()
+			gc := NewGarbageCollector(tc.fields.kube, parentDir, WithFs(tc.fields.fs), WithMetrics(m))
+			err := gc.Restore(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ngc.Restore(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+
+			got := getDirs(tc.fields.fs, parentDir)
+			if diff := cmp.Diff(tc.want.dirs, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\ngc.Restore(...): -want dirs, +got dirs:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.scanned, testutil.ToFloat64(m.scanned.WithLabelValues(parentDir))); diff != "" {
+				t.Errorf("\n%s\ngc.Restore(...): -want scanned, +got scanned:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.removed, testutil.ToFloat64(m.removed.WithLabelValues(parentDir))); diff != "" {
+				t.Errorf("\n%s\ngc.Restore(...): -want removed, +got removed:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	_ = fs.WriteFile("/tf/a/main.tf", []byte("12345"), 0600)
+	_ = fs.WriteFile("/tf/a/.terraform/modules/modules.json", []byte("123"), 0600)
+	_ = fs.WriteFile("/tmp/tf/a/.git-credentials", []byte("12"), 0600)
+
+	got, err := Usage(fs, "/tf/a", "/tmp/tf/a", "/tf/missing")
+	if err != nil {
+		t.Fatalf("Usage(...): %v", err)
+	}
+	if diff := cmp.Diff(int64(10), got); diff != "" {
+		t.Errorf("Usage(...): -want, +got:\n%s", diff)
+	}
+}